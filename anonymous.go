@@ -0,0 +1,81 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"regexp"
+	"strings"
+
+	"golang.design/x/research/internal/post"
+	"golang.design/x/research/internal/xref"
+)
+
+const anonymousAuthor = "Anonymous Author(s)"
+
+// anonymize removes the authors from the given metadata and the
+// identifying document properties, i.e. the author names and the
+// canonical URL of the article.
+func anonymize(metaData map[string]any, props *properties) {
+	metaData["author"] = []string{anonymousAuthor}
+	props.Authors = []string{anonymousAuthor}
+	props.URL = ""
+}
+
+// maskSelfCitations replaces the names and emails of the given authors
+// in the bibliography so that self-citations do not reveal the authors.
+// The references of other posts of the site, which cross-references
+// produce, are masked as well: their venue is replaced, and their URLs
+// under the baseURL of the site are removed.
+func maskSelfCitations(references string, authors []post.Author, baseURL string) string {
+	references = strings.ReplaceAll(references, xref.Venue, "Anonymous")
+	if baseURL != "" {
+		re := regexp.MustCompile(`[ \t]*\\url\{` + regexp.QuoteMeta(baseURL) + `[^}]*\}`)
+		references = re.ReplaceAllString(references, "")
+	}
+	for _, a := range authors {
+		for _, m := range [][2]string{
+			{a.Name, "Anonymous"},
+			{a.Email, "anonymous"},
+			{strings.ReplaceAll(a.Email, "@", "[at]"), "anonymous"},
+		} {
+			if m[0] != "" {
+				references = strings.ReplaceAll(references, m[0], m[1])
+			}
+		}
+	}
+	return references
+}
+
+// reportIdentities reports every remaining occurrence of the authors'
+// names or emails in the abstract and the body of the given markdown.
+// These occurrences are not masked automatically because they are
+// likely part of a sentence that needs to be rephrased by hand.
+func reportIdentities(path string, b []byte, authors []post.Author) {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	inContent := false
+	for n := 1; s.Scan(); n++ {
+		l := s.Text()
+		switch {
+		case strings.HasPrefix(l, "<!--abstract-->"):
+			inContent = true
+			continue
+		case strings.HasPrefix(l, "## References"):
+			return
+		}
+		if !inContent {
+			continue
+		}
+
+		for _, a := range authors {
+			for _, id := range []string{a.Name, a.Email, strings.ReplaceAll(a.Email, "@", "[at]")} {
+				if id != "" && strings.Contains(l, id) {
+					log.Printf("pdfgen: %s:%d: found author identity %q", path, n, id)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.design/x/research/internal/post"
+	"golang.design/x/research/internal/xref"
+)
+
+func TestMaskSelfCitations(t *testing.T) {
+	refs := "Jane Doe. Contact jane@example.com or jane[at]example.com. Bob.\n"
+	for _, tt := range []struct {
+		name    string
+		authors []post.Author
+		want    string
+	}{
+		{"name-email", []post.Author{{Name: "Jane Doe", Email: "jane@example.com"}},
+			"Anonymous. Contact anonymous or anonymous. Bob.\n"},
+		{"no-email", []post.Author{{Name: "Jane Doe"}},
+			"Anonymous. Contact jane@example.com or jane[at]example.com. Bob.\n"},
+		{"no-name", []post.Author{{Email: "jane@example.com"}},
+			"Jane Doe. Contact anonymous or anonymous. Bob.\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maskSelfCitations(refs, tt.authors, ""); got != tt.want {
+				t.Fatalf("maskSelfCitations: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskCrossReferences(t *testing.T) {
+	refs := bibliography([]post.Reference{{
+		Key:  "research:bench-time",
+		Text: "Changkun Ou, Jane Doe. 2020. Eliminating A Source of Measurement Errors in Benchmarks. The golang.design Research. September 30. https://golang.design/research/bench-time",
+	}, {
+		Key:  "ou2020bench",
+		Text: "Changkun Ou. 2020. Conduct Reliable Benchmarking in Go. https://golang.design/s/gobench",
+	}})
+	got := maskSelfCitations(refs, []post.Author{{Name: "Changkun Ou"}}, "https://golang.design/research")
+	for _, want := range []string{
+		"\\bibitem{research:bench-time} Anonymous, Jane Doe. 2020. Eliminating A Source of Measurement Errors in Benchmarks. Anonymous. September 30.\n",
+		"\\bibitem{ou2020bench} Anonymous. 2020. Conduct Reliable Benchmarking in Go. \\url{https://golang.design/s/gobench}\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("maskSelfCitations: got\n%s\nwant it to contain\n%s", got, want)
+		}
+	}
+	if strings.Contains(got, xref.Venue) {
+		t.Fatalf("maskSelfCitations: got\n%s\nwant no %q", got, xref.Venue)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.design/x/research/internal/post"
+)
+
+// assetIgnore are the default patterns of files that are not attached
+// to the PDF. Hidden files are irrelevant, and figures are already part
+// of the article. Additional patterns, one per line, can be listed in a
+// .pdfgenignore file in the asset directory.
+var assetIgnore = []string{".*", "*.png", "*.jpg", "*.jpeg", "*.gif", "*.pdf"}
+
+// assetFile is a file of the companion source code of an article.
+type assetFile struct {
+	Path string
+	Size int64
+	Hash string
+}
+
+// assetDir returns the companion asset directory of the markdown file
+// at the given path, i.e. content/assets/<slug>, or an empty string if
+// the article does not have one.
+func assetDir(mdpath string, metaData map[string]any) string {
+	dir := filepath.Join(filepath.Dir(mdpath), "..", "assets", post.Slug(mdpath, metaData))
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return ""
+	}
+	return dir
+}
+
+// packAssets writes the files in dir to a zip archive at dst, and
+// returns the list of packed files. All files are stored under the
+// name of dir, with the given modification time so that the archive
+// only changes if the assets change.
+func packAssets(dir, dst string, modified time.Time) []assetFile {
+	f, err := os.Create(dst)
+	if err != nil {
+		log.Fatalf("pdfgen: cannot create asset archive: %v", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	root := filepath.Base(dir)
+	files := walkAssets(dir, func(rel string, b []byte) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     path.Join(root, rel),
+			Method:   zip.Deflate,
+			Modified: modified,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	})
+	if err := zw.Close(); err != nil {
+		log.Fatalf("pdfgen: cannot pack assets of %v: %v", dir, err)
+	}
+	return files
+}
+
+// walkAssets calls fn for every file in dir that is not ignored, using
+// the slash separated path relative to dir, and returns the list of
+// visited files.
+func walkAssets(dir string, fn func(rel string, b []byte) error) []assetFile {
+	ignore := append([]string{}, assetIgnore...)
+	if b, err := os.ReadFile(filepath.Join(dir, ".pdfgenignore")); err == nil {
+		s := bufio.NewScanner(bytes.NewReader(b))
+		for s.Scan() {
+			l := strings.TrimSpace(s.Text())
+			if l != "" && !strings.HasPrefix(l, "#") {
+				ignore = append(ignore, l)
+			}
+		}
+	}
+
+	files := []assetFile{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if ignored(ignore, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if err := fn(rel, b); err != nil {
+			return err
+		}
+		files = append(files, assetFile{
+			Path: rel,
+			Size: int64(len(b)),
+			Hash: fmt.Sprintf("%x", sha256.Sum256(b)),
+		})
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("pdfgen: cannot walk assets of %v: %v", dir, err)
+	}
+	return files
+}
+
+// ignored reports whether the given slash separated path matches one
+// of the patterns, either by its base name or by its full path.
+func ignored(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		p = strings.TrimSuffix(p, "/")
+		if ok, _ := path.Match(p, path.Base(rel)); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// assetAppendix returns the appendix of an article that embeds the
+// given archive into the PDF and lists the packed files.
+func assetAppendix(archive string, files []assetFile) string {
+	var w strings.Builder
+	fmt.Fprintf(&w, `\appendix
+
+## Companion Source Code
+
+\embedfile[desc={Companion source code and benchmark results}]{%v}
+
+The companion source code of this article, including the runnable Go
+modules and the raw benchmark results, is attached to this PDF as
+`+"`%v`"+`. The archive contains the following files:
+
+`, archive, archive)
+	for _, f := range files {
+		fmt.Fprintf(&w, "- `%v` (%d bytes)\\\n  SHA-256: `%v`\n", f.Path, f.Size, f.Hash)
+	}
+	return w.String()
+}
+
+// writeAssetAppendix packs the assets in dir into the working
+// directory and writes the appendix that attaches them. It returns the
+// names of the created files, which must be removed after use.
+func writeAssetAppendix(dir string, modified time.Time) (archive, appendix string) {
+	archive = filepath.Base(dir) + ".zip"
+	files := packAssets(dir, archive, modified)
+
+	appendix = "appendix.md"
+	if err := os.WriteFile(appendix, []byte(assetAppendix(archive, files)), os.ModePerm); err != nil {
+		log.Fatalf("pdfgen: cannot create appendix: %v", err)
+	}
+	return archive, appendix
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWalkAssets(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		ignore string // the content of .pdfgenignore, if any
+		want   []string
+	}{
+		{"default", "", []string{"bench/result.txt", "go.mod", "main.go", "testdata/in.txt"}},
+		{"pdfgenignore", "# generated\n\ntestdata/\n*.txt\n", []string{"go.mod", "main.go"}},
+		{"path", "bench/result.txt\n", []string{"go.mod", "main.go", "testdata/in.txt"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{
+				"go.mod":           "module x\n",
+				"main.go":          "package main\n",
+				"bench/result.txt": "BenchmarkX 1 1 ns/op\n",
+				"testdata/in.txt":  "in\n",
+				"figure.png":       "png",
+				"paper.pdf":        "pdf",
+				".git/config":      "[core]\n",
+				".DS_Store":        "",
+			}
+			if tt.ignore != "" {
+				files[".pdfgenignore"] = tt.ignore
+			}
+			for name, content := range files {
+				p := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			visited := []string{}
+			got := walkAssets(dir, func(rel string, b []byte) error {
+				if string(b) != files[rel] {
+					t.Fatalf("walkAssets: got %q for %v, want %q", b, rel, files[rel])
+				}
+				visited = append(visited, rel)
+				return nil
+			})
+			if !reflect.DeepEqual(visited, tt.want) {
+				t.Fatalf("walkAssets: visited %v, want %v", visited, tt.want)
+			}
+			paths := []string{}
+			for _, f := range got {
+				paths = append(paths, f.Path)
+				if f.Size != int64(len(files[f.Path])) {
+					t.Fatalf("walkAssets: got size %d for %v, want %d", f.Size, f.Path, len(files[f.Path]))
+				}
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Fatalf("walkAssets: got %v, want %v", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	patterns := []string{".*", "*.png", "testdata/", "bench/*.txt"}
+	for _, tt := range []struct {
+		rel  string
+		want bool
+	}{
+		{".git", true},
+		{"sub/.env", true},
+		{"img/a.png", true},
+		{"testdata", true},
+		{"bench/result.txt", true},
+		{"result.txt", false},
+		{"sub/bench/result.txt", false},
+		{"main.go", false},
+	} {
+		if got := ignored(patterns, tt.rel); got != tt.want {
+			t.Fatalf("ignored(%v): got %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestAssetAppendix(t *testing.T) {
+	got := assetAppendix("bench-time.zip", []assetFile{
+		{Path: "go.mod", Size: 9, Hash: "fc4a3fdf"},
+		{Path: "bench/result.txt", Size: 21, Hash: "8f8cbb7d"},
+	})
+	for _, want := range []string{
+		"\\appendix\n",
+		"\\embedfile[desc={Companion source code and benchmark results}]{bench-time.zip}",
+		"is attached to this PDF as\n`bench-time.zip`.",
+		"- `go.mod` (9 bytes)\\\n  SHA-256: `fc4a3fdf`\n- `bench/result.txt` (21 bytes)\\\n  SHA-256: `8f8cbb7d`\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("assetAppendix: got\n%s\nwant it to contain\n%s", got, want)
+		}
+	}
+}
+
+func TestAssetDir(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"posts", "assets/bench-time", "assets/pointer-params"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(name)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range []struct {
+		name     string
+		file     string
+		metaData map[string]any
+		want     string // relative to root
+	}{
+		{"slug", "2020-bench.md", map[string]any{"slug": "/bench-time"}, "assets/bench-time"},
+		{"file-name", "pointer-params.md", map[string]any{}, "assets/pointer-params"},
+		{"none", "generic-option.md", map[string]any{}, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			want := ""
+			if tt.want != "" {
+				want = filepath.Join(root, filepath.FromSlash(tt.want))
+			}
+			if got := assetDir(filepath.Join(root, "posts", tt.file), tt.metaData); got != want {
+				t.Fatalf("assetDir: got %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.design/x/research/internal/benchchart"
+	"golang.design/x/research/internal/benchstat"
+	"golang.design/x/research/internal/site"
+)
+
+// benchTables replaces the benchtable shortcodes in the body of the
+// markdown file at path with the tables of the benchmark results as
+// rendered by render, e.g. latexTables.
+func benchTables(path, body string, render func([]*benchstat.Table) string) string {
+	scs := benchstat.Shortcodes([]byte(body))
+	if len(scs) == 0 {
+		return body
+	}
+	root, err := site.Root(filepath.Dir(path))
+	if err != nil {
+		log.Fatalf("pdfgen: cannot locate benchmark files: %v", err)
+	}
+	for _, sc := range scs {
+		files, err := benchstat.Load(root, sc.Inputs)
+		if err != nil {
+			log.Fatalf("pdfgen: benchtable: %v", err)
+		}
+		body = strings.Replace(body, sc.Text, render(benchstat.Tables(files)), 1)
+	}
+	return body
+}
+
+// latexTables renders tables as a raw LaTeX block of pandoc.
+func latexTables(ts []*benchstat.Table) string {
+	var b strings.Builder
+	b.WriteString("```{=latex}\n")
+	for _, t := range ts {
+		b.WriteString(latexTable(t))
+	}
+	b.WriteString("```")
+	return b.String()
+}
+
+// latexTable renders a table as a LaTeX float.
+func latexTable(t *benchstat.Table) string {
+	cell := strings.NewReplacer("±", `$\pm$`, "µ", `$\mu$`)
+	row := func(cells []string, name func(string) string) string {
+		esc := make([]string, len(cells))
+		for i, c := range cells {
+			esc[i] = cell.Replace(texText(c))
+			if i == 0 {
+				esc[i] = name(esc[i])
+			}
+		}
+		return strings.Join(esc, " & ") + ` \\` + "\n"
+	}
+
+	var b strings.Builder
+	b.WriteString("\\begin{table}[htbp]\n\\centering\n\\footnotesize\n")
+	fmt.Fprintf(&b, "\\begin{tabular}{l%s}\n\\hline\n", strings.Repeat("r", len(t.Header)-1))
+	b.WriteString(row(t.Header, func(s string) string { return s }))
+	b.WriteString("\\hline\n")
+	for _, r := range t.Rows {
+		b.WriteString(row(r, func(s string) string { return `\texttt{` + s + `}` }))
+	}
+	b.WriteString("\\hline\n\\end{tabular}\n")
+	fmt.Fprintf(&b, "\\caption{%s}\n\\end{table}\n", cell.Replace(texText(t.Caption)))
+	return b.String()
+}
+
+// textTables renders tables as indented code blocks with aligned
+// columns, each preceded by its caption.
+func textTables(ts []*benchstat.Table) string {
+	var b strings.Builder
+	for _, t := range ts {
+		widths := make([]int, len(t.Header))
+		for _, r := range append([][]string{t.Header}, t.Rows...) {
+			for i, c := range r {
+				if n := utf8.RuneCountInString(c); i < len(widths) && n > widths[i] {
+					widths[i] = n
+				}
+			}
+		}
+		fmt.Fprintf(&b, "\n%s\n\n", t.Caption)
+		for _, r := range append([][]string{t.Header}, t.Rows...) {
+			cells := make([]string, len(r))
+			for i, c := range r {
+				pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c))
+				if i == 0 {
+					cells[i] = c + pad
+				} else {
+					cells[i] = pad + c
+				}
+			}
+			fmt.Fprintf(&b, "\t%s\n", strings.TrimRight(strings.Join(cells, "  "), " "))
+		}
+	}
+	return b.String()
+}
+
+// benchCharts replaces the benchchart shortcodes in the body of the
+// markdown file at path with TikZ pictures of the charts, and reports
+// whether there are any. The alternative text of a chart, or else its
+// title, is attached to the picture as a tooltip.
+func benchCharts(path, body string) (string, bool) {
+	scs := benchchart.Shortcodes([]byte(body))
+	if len(scs) == 0 {
+		return body, false
+	}
+	root, err := site.Root(filepath.Dir(path))
+	if err != nil {
+		log.Fatalf("pdfgen: cannot locate benchmark files: %v", err)
+	}
+	for _, sc := range scs {
+		c, err := benchchart.Build(root, sc)
+		if err != nil {
+			log.Fatalf("pdfgen: %v", err)
+		}
+		alt := sc.Params["alt"]
+		if alt == "" {
+			alt = c.Title
+		}
+		tex := "```{=latex}\n\\begin{figure}[htbp]\n\\centering\n" + tooltip(strings.TrimSuffix(c.TikZ(), "\n"), alt) + "\n\\end{figure}\n```"
+		body = strings.Replace(body, sc.Text, tex, 1)
+	}
+	return body, true
+}
@@ -0,0 +1,28 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.design/x/research/internal/benchstat"
+)
+
+func TestLatexTable(t *testing.T) {
+	got := latexTable(&benchstat.Table{
+		Caption: `time/op of a_b {x} & $y ~ 50% #1`,
+		Header:  []string{"name", "old time/op", "delta"},
+		Rows:    [][]string{{"Fields_{2}", "1.00µs ± 2%", "~"}},
+	})
+	for _, want := range []string{
+		"name & old time/op & delta \\\\\n",
+		`\texttt{Fields\_\{2\}} & 1.00$\mu$s $\pm$ 2\% & \textasciitilde{} \\`,
+		`\caption{time/op of a\_b \{x\} \& \$y \textasciitilde{} 50\% \#1}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("latexTable: got\n%s\nwant it to contain\n%s", got, want)
+		}
+	}
+}
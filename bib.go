@@ -0,0 +1,56 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"golang.design/x/research/internal/bib"
+	"golang.design/x/research/internal/post"
+)
+
+// checkBibliography checks the references of all posts in dir against
+// the site-level bibliography. It reports citations that are defined
+// nowhere, and keys that are defined differently across the posts and
+// the bibliography.
+func checkBibliography(dir string) {
+	bibl, err := bib.Load(dir)
+	if err != nil {
+		log.Fatalf("pdfgen: cannot load bibliography: %v", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		log.Fatalf("pdfgen: cannot find posts: %v", err)
+	}
+
+	failed := false
+	posts := map[string][]post.Reference{}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatalf("pdfgen: failed to load %v: %v", file, err)
+		}
+		refs, err := post.References(b)
+		if err != nil {
+			log.Printf("pdfgen: %v: %v", file, err)
+			failed = true
+			continue
+		}
+		if _, err := bibl.Resolve(refs, post.Citations(b)); err != nil {
+			log.Printf("pdfgen: %v: %v", file, err)
+			failed = true
+		}
+		posts[file] = refs
+	}
+	for _, c := range bibl.Conflicts(posts) {
+		log.Printf("pdfgen: %v", c)
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+	log.Printf("pdfgen: references of %d posts are consistent with %v", len(files), bib.Path)
+}
@@ -0,0 +1,266 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.design/x/research/internal/post"
+	"golang.design/x/research/internal/xref"
+	"gopkg.in/yaml.v3"
+)
+
+// bundle produces a self-contained archive of the markdown file at the
+// given path for long-term preservation. The archive is written next
+// to the generated PDF and contains:
+//
+//	<slug>/<slug>.pdf       the article
+//	<slug>/<slug>.tex       the LaTeX sources of the article
+//	<slug>/references.tex   the bibliography
+//	<slug>/figures/         the figures used by the article
+//	<slug>/assets/          the companion Go modules and benchmark results
+//	<slug>/CITATION.cff     the citation metadata
+//	<slug>/MANIFEST         the SHA-256 checksums of all files above
+func bundle(mdpath string) {
+	if *anonymous {
+		log.Fatalf("pdfgen: an archival bundle cannot be anonymous.")
+	}
+
+	a := loadArticle(mdpath)
+	slug := post.Slug(mdpath, a.metaData)
+
+	tmp, err := os.MkdirTemp("", "pdfgen-bundle")
+	if err != nil {
+		log.Fatalf("pdfgen: cannot create bundle directory: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, slug)
+
+	mkdir := func(name string) string {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(p, os.ModePerm); err != nil {
+			log.Fatalf("pdfgen: cannot create bundle directory: %v", err)
+		}
+		return p
+	}
+	write := func(name string, b []byte) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		mkdir(path.Dir(name))
+		if err := os.WriteFile(p, b, 0644); err != nil {
+			log.Fatalf("pdfgen: cannot write %v to bundle: %v", name, err)
+		}
+	}
+
+	mkdir(".")
+	a.render(filepath.Join(dir, slug+".pdf"))
+
+	// The LaTeX sources refer to the bundled figures instead of the
+	// assets of the website, and the companion source code is bundled
+	// as plain files rather than a PDF attachment.
+	src := *a
+	src.assets = ""
+	for _, fig := range parseFigures(a.body) {
+		b, err := os.ReadFile(filepath.Join(filepath.Dir(mdpath), fig))
+		if err != nil {
+			log.Fatalf("pdfgen: cannot read figure: %v", err)
+		}
+		name := "figures/" + path.Base(fig)
+		write(name, b)
+		src.body = strings.ReplaceAll(src.body, "]("+fig+")", "]("+name+")")
+	}
+	src.render(filepath.Join(dir, slug+".tex"), "--standalone")
+	write("references.tex", []byte(a.references))
+
+	if assets := assetDir(mdpath, a.metaData); assets != "" {
+		walkAssets(assets, func(rel string, b []byte) error {
+			write("assets/"+rel, b)
+			return nil
+		})
+	}
+
+	cff, err := citation(a)
+	if err != nil {
+		log.Fatalf("pdfgen: cannot create citation file: %v", err)
+	}
+	write("CITATION.cff", cff)
+	write("MANIFEST", manifest(dir))
+
+	dst := "../" + slug + ".tar.gz"
+	if err := writeTarball(tmp, dst); err != nil {
+		log.Fatalf("pdfgen: cannot create bundle: %v", err)
+	}
+	log.Printf("pdfgen: bundle written to %v", dst)
+}
+
+var reFigure = regexp.MustCompile(`!\[[^\]]*\]\(([^)\s]+)`)
+
+// parseFigures returns the paths of the local figures in the body.
+func parseFigures(body string) []string {
+	figs := []string{}
+	for _, m := range reFigure.FindAllStringSubmatch(body, -1) {
+		if strings.Contains(m[1], "://") {
+			continue
+		}
+		figs = append(figs, m[1])
+	}
+	return figs
+}
+
+// citationFile is the Citation File Format (CFF) of an article, see
+// https://citation-file-format.github.io.
+type citationFile struct {
+	CFFVersion        string           `yaml:"cff-version"`
+	Message           string           `yaml:"message"`
+	Type              string           `yaml:"type"`
+	Title             string           `yaml:"title"`
+	Authors           []citationAuthor `yaml:"authors"`
+	Abstract          string           `yaml:"abstract,omitempty"`
+	Keywords          []string         `yaml:"keywords,omitempty"`
+	DateReleased      string           `yaml:"date-released"`
+	URL               string           `yaml:"url,omitempty"`
+	PreferredCitation citationRef      `yaml:"preferred-citation"`
+}
+
+type citationAuthor struct {
+	FamilyNames string `yaml:"family-names"`
+	GivenNames  string `yaml:"given-names,omitempty"`
+	Email       string `yaml:"email,omitempty"`
+}
+
+type citationRef struct {
+	Type    string           `yaml:"type"`
+	Title   string           `yaml:"title"`
+	Authors []citationAuthor `yaml:"authors"`
+	Journal string           `yaml:"journal"`
+	Year    int              `yaml:"year"`
+	Month   int              `yaml:"month"`
+	URL     string           `yaml:"url,omitempty"`
+}
+
+// citation returns the CITATION.cff of the given article.
+func citation(a *article) ([]byte, error) {
+	authors := []citationAuthor{}
+	for _, au := range a.authors {
+		given, family := "", au.Name
+		if i := strings.LastIndex(au.Name, " "); i >= 0 {
+			given, family = au.Name[:i], au.Name[i+1:]
+		}
+		authors = append(authors, citationAuthor{
+			FamilyNames: family,
+			GivenNames:  given,
+			Email:       au.Email,
+		})
+	}
+	return yaml.Marshal(citationFile{
+		CFFVersion:   "1.2.0",
+		Message:      "If you use this article, please cite it as below.",
+		Type:         "dataset",
+		Title:        a.props.Title,
+		Authors:      authors,
+		Abstract:     a.props.Subject,
+		Keywords:     a.props.Keywords,
+		DateReleased: a.date.Format("2006-01-02"),
+		URL:          a.props.URL,
+		PreferredCitation: citationRef{
+			Type:    "article",
+			Title:   a.props.Title,
+			Authors: authors,
+			Journal: xref.Venue,
+			Year:    a.date.Year(),
+			Month:   int(a.date.Month()),
+			URL:     a.props.URL,
+		},
+	})
+}
+
+// manifest returns the SHA-256 checksums of all files in dir in the
+// format of sha256sum(1), so that the bundle can be verified using
+// sha256sum -c MANIFEST.
+func manifest(dir string) []byte {
+	var w strings.Builder
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&w, "%x  %v\n", sha256.Sum256(b), filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("pdfgen: cannot create manifest: %v", err)
+	}
+	return []byte(w.String())
+}
+
+// writeTarball writes all files in dir to a gzip compressed tarball at dst.
+func writeTarball(dir, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(b)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.design/x/research/internal/post"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseFigures(t *testing.T) {
+	body := "![](./assets/a.png)\n![A chart](assets/b.svg \"title\")\n![](https://example.com/c.png)\n"
+	want := []string{"./assets/a.png", "assets/b.svg"}
+	if got := parseFigures(body); !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseFigures: got %v, want %v", got, want)
+	}
+}
+
+func TestCitation(t *testing.T) {
+	a := &article{
+		date: time.Date(2020, time.September, 30, 9, 2, 20, 0, time.UTC),
+		authors: []post.Author{
+			{Name: "Changkun Ou", Email: "research@changkun.de"},
+			{Name: "Mary Jane Doe"},
+			{Name: "Plato"},
+		},
+		props: properties{
+			Title:    "Eliminating A Source of Measurement Errors in Benchmarks",
+			Subject:  "About six months ago, I did a presentation.",
+			Keywords: []string{"Benchmark"},
+			URL:      "https://golang.design/research/bench-time",
+		},
+	}
+	b, err := citation(a)
+	if err != nil {
+		t.Fatalf("citation: %v", err)
+	}
+	var got citationFile
+	if err := yaml.Unmarshal(b, &got); err != nil {
+		t.Fatalf("citation: invalid YAML: %v\n%s", err, b)
+	}
+
+	authors := []citationAuthor{
+		{FamilyNames: "Ou", GivenNames: "Changkun", Email: "research@changkun.de"},
+		{FamilyNames: "Doe", GivenNames: "Mary Jane"},
+		{FamilyNames: "Plato"},
+	}
+	for _, tt := range []struct {
+		field     string
+		got, want any
+	}{
+		{"cff-version", got.CFFVersion, "1.2.0"},
+		{"type", got.Type, "dataset"},
+		{"title", got.Title, a.props.Title},
+		{"authors", got.Authors, authors},
+		{"abstract", got.Abstract, a.props.Subject},
+		{"keywords", got.Keywords, a.props.Keywords},
+		{"date-released", got.DateReleased, "2020-09-30"},
+		{"url", got.URL, a.props.URL},
+		{"preferred-citation", got.PreferredCitation, citationRef{
+			Type:    "article",
+			Title:   a.props.Title,
+			Authors: authors,
+			Journal: "The golang.design Research",
+			Year:    2020,
+			Month:   9,
+			URL:     a.props.URL,
+		}},
+	} {
+		t.Run(tt.field, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Fatalf("citation: got %v %+v, want %+v", tt.field, tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.pdf":              "pdf",
+		"figures/b.png":      "png",
+		"assets/go.mod":      "module x\n",
+		"assets/empty/.keep": "",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The lines are sorted by path, in the format of sha256sum(1).
+	want := "c35b21d6ca39aa7cc3b79a705d989f1a6e88b99ab43988d74048799e3db926a3  a.pdf\n" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  assets/empty/.keep\n" +
+		"fc4a3fdfa1b8230e721b7eaada825923cc3b4219dee283230bcec3f6be74c274  assets/go.mod\n" +
+		"8f8cbb7dcf46e0bc7d53265749a6c17d116093a6ba95e442764060c76fd4a86c  figures/b.png\n"
+	if got := string(manifest(dir)); got != want {
+		t.Fatalf("manifest: got\n%s\nwant\n%s", got, want)
+	}
+}
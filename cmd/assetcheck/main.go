@@ -0,0 +1,430 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+// Assetcheck verifies that the companion modules of golang.design
+// research posts still build, vet and test with a given Go release,
+// and writes a compatibility report.
+//
+// Usage:
+//
+//	assetcheck [flags] [dir]
+//
+// Assetcheck discovers every go.mod in dir (default content/assets),
+// and runs go build, go vet and go test for all packages of each
+// module. The modules are checked as they are: go.mod and go.sum are
+// never updated, and a module whose go.mod needs updating fails.
+//
+// A package that needs a capability which is not available in every
+// environment, such as a display for a window, declares it with a
+// directive in one of its files:
+//
+//	//research:requires display
+//
+// Such packages, as well as the packages that import them, are skipped
+// unless the capability is enabled with -with. The display capability
+// is enabled by default if the DISPLAY or WAYLAND_DISPLAY environment
+// variable is set.
+//
+// The report is a markdown document that lists the result for each
+// module, and is meant to be attached to the posts, e.g. as "verified
+// with go1.18". Assetcheck exits with a non-zero status if any check
+// fails.
+//
+// The flags are:
+//
+//	-go string
+//		the go command used to check the modules (default "go")
+//	-o file
+//		write the report to file instead of standard output
+//	-timeout duration
+//		the timeout of the tests of each module (default 10m)
+//	-with list
+//		comma-separated list of available capabilities
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	goCmd   = flag.String("go", "go", "the go command used to check the modules")
+	outFile = flag.String("o", "", "write the report to `file` instead of standard output")
+	timeout = flag.Duration("timeout", 10*time.Minute, "the timeout of the tests of each module")
+	with    = flag.String("with", defaultCapabilities(), "comma-separated `list` of available capabilities")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: assetcheck [flags] [dir]\n")
+	flag.PrintDefaults()
+}
+
+// defaultCapabilities returns the capabilities that are detected from
+// the environment.
+func defaultCapabilities() string {
+	if os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != "" {
+		return "display"
+	}
+	return ""
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	dir := "content/assets"
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dir = flag.Arg(0)
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	caps := map[string]bool{}
+	for _, c := range strings.Split(*with, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			caps[c] = true
+		}
+	}
+
+	mods, err := findModules(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "assetcheck: %v\n", err)
+		os.Exit(1)
+	}
+	version, err := goVersion()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "assetcheck: %v\n", err)
+		os.Exit(1)
+	}
+
+	results := make([]result, 0, len(mods))
+	for _, m := range mods {
+		fmt.Fprintf(os.Stderr, "checking %s\n", m)
+		results = append(results, check(m, caps))
+	}
+
+	var buf bytes.Buffer
+	writeReport(&buf, dir, version, time.Now(), results)
+	if *outFile != "" {
+		err = os.WriteFile(*outFile, buf.Bytes(), 0644)
+	} else {
+		_, err = os.Stdout.Write(buf.Bytes())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "assetcheck: %v\n", err)
+		os.Exit(1)
+	}
+	for _, r := range results {
+		if !r.ok() {
+			os.Exit(1)
+		}
+	}
+}
+
+// findModules returns the directories that contain a go.mod in dir,
+// in lexical order.
+func findModules(dir string) ([]string, error) {
+	mods := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && d.Name() == "go.mod" {
+			mods = append(mods, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(mods)
+	return mods, nil
+}
+
+// goVersion returns the version and platform of the go command,
+// e.g. "go1.18 linux/amd64".
+func goVersion() (string, error) {
+	out, err := goCommand(".", "env", "GOVERSION", "GOOS", "GOARCH").Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine go version: %v", err)
+	}
+	f := strings.Fields(string(out))
+	if len(f) != 3 {
+		return "", fmt.Errorf("cannot determine go version: %q", out)
+	}
+	return fmt.Sprintf("%s %s/%s", f[0], f[1], f[2]), nil
+}
+
+// A step is a check of a module, i.e. go build, go vet or go test.
+type step struct {
+	Ok     bool
+	Output string
+}
+
+// A result is the result of checking a module.
+type result struct {
+	Dir     string
+	Module  string
+	Error   string // the module cannot be checked at all
+	Skipped []string
+	Build   step
+	Vet     step
+	Test    step
+}
+
+func (r result) ok() bool {
+	return r.Error == "" && r.Build.Ok && r.Vet.Ok && r.Test.Ok
+}
+
+// check builds, vets and tests the packages of the module in dir that
+// only require the given capabilities.
+func check(dir string, caps map[string]bool) result {
+	r := result{Dir: dir}
+
+	out, err := goCommand(dir, "list", "-m").Output()
+	if err != nil {
+		r.Error = fmt.Sprintf("go list -m: %v", err)
+		return r
+	}
+	r.Module = strings.TrimSpace(string(out))
+
+	pkgs, err := listPackages(dir)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	for _, p := range pkgs {
+		if err := modUpdate(p); err != "" {
+			r.Error = "go.mod needs updating, run go mod tidy: " + err
+			return r
+		}
+	}
+	run, skipped := selectPackages(pkgs, caps)
+	r.Skipped = skipped
+	if len(run) == 0 {
+		r.Build.Ok, r.Vet.Ok, r.Test.Ok = true, true, true
+		return r
+	}
+
+	// Packages that only contain tests are checked by go vet and go
+	// test, but cannot be built.
+	build := []string{"build", "-o", os.DevNull}
+	for _, p := range pkgs {
+		if len(p.GoFiles)+len(p.CgoFiles) > 0 && contains(run, p.ImportPath) {
+			build = append(build, p.ImportPath)
+		}
+	}
+	r.Build = step{Ok: true}
+	if len(build) > 3 {
+		r.Build = runStep(dir, build...)
+	}
+	r.Vet = runStep(dir, append([]string{"vet"}, run...)...)
+	r.Test = runStep(dir, append([]string{"test", "-count=1", "-timeout", timeout.String()}, run...)...)
+	return r
+}
+
+func contains(s []string, x string) bool {
+	for _, v := range s {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
+
+// runStep runs the go command with the given arguments in dir.
+func runStep(dir string, args ...string) step {
+	out, err := goCommand(dir, args...).CombinedOutput()
+	return step{Ok: err == nil, Output: string(out)}
+}
+
+// A pkg is a package of a module, as reported by go list.
+type pkg struct {
+	Dir          string
+	ImportPath   string
+	GoFiles      []string
+	CgoFiles     []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Deps         []string
+	Error        *pkgError
+	DepsErrors   []*pkgError
+
+	requires []string
+}
+
+// A pkgError is an error of loading a package, as reported by go list.
+type pkgError struct {
+	Err string
+}
+
+var reModUpdate = regexp.MustCompile(`updates to go\.mod needed|missing go\.sum entry|disabled by -mod=readonly`)
+
+// modUpdate returns the error of a package that cannot be loaded
+// unless go.mod or go.sum are updated, or "" otherwise.
+func modUpdate(p *pkg) string {
+	errs := append([]*pkgError{p.Error}, p.DepsErrors...)
+	for _, e := range errs {
+		if e != nil && reModUpdate.MatchString(e.Err) {
+			return e.Err
+		}
+	}
+	return ""
+}
+
+// listPackages returns the packages of the module in dir, along with
+// the capabilities that each package requires.
+func listPackages(dir string) ([]*pkg, error) {
+	cmd := goCommand(dir, "list", "-e", "-json", "./...")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %v", err)
+	}
+
+	pkgs := []*pkg{}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		p := &pkg{}
+		if err := dec.Decode(p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list: %v", err)
+		}
+
+		files := [][]string{p.GoFiles, p.CgoFiles, p.TestGoFiles, p.XTestGoFiles}
+		for _, names := range files {
+			for _, name := range names {
+				b, err := os.ReadFile(filepath.Join(p.Dir, name))
+				if err != nil {
+					return nil, err
+				}
+				p.requires = append(p.requires, requirements(b)...)
+			}
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+var reRequires = regexp.MustCompile(`(?m)^//research:requires\s+(.+?)\s*$`)
+
+// requirements returns the capabilities declared by the
+// //research:requires directives of a Go file.
+func requirements(b []byte) []string {
+	caps := []string{}
+	for _, m := range reRequires.FindAllSubmatch(b, -1) {
+		for _, c := range strings.FieldsFunc(string(m[1]), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		}) {
+			caps = append(caps, c)
+		}
+	}
+	return caps
+}
+
+// selectPackages returns the import paths of the packages that only
+// require the given capabilities, and a description of the skipped
+// packages. A package is skipped if it, or any package of the module
+// that it imports, requires a missing capability.
+func selectPackages(pkgs []*pkg, caps map[string]bool) (run, skipped []string) {
+	missing := map[string]string{} // import path to missing capability
+	for _, p := range pkgs {
+		for _, c := range p.requires {
+			if !caps[c] {
+				missing[p.ImportPath] = c
+				break
+			}
+		}
+	}
+
+	run, skipped = []string{}, []string{}
+	for _, p := range pkgs {
+		c, ok := missing[p.ImportPath]
+		for _, dep := range p.Deps {
+			if ok {
+				break
+			}
+			c, ok = missing[dep]
+		}
+		if ok {
+			skipped = append(skipped, fmt.Sprintf("%s (requires %s)", p.ImportPath, c))
+			continue
+		}
+		run = append(run, p.ImportPath)
+	}
+	return run, skipped
+}
+
+// writeReport writes the compatibility report of the results.
+func writeReport(w io.Writer, dir, version string, date time.Time, results []result) {
+	status := func(s step) string {
+		if s.Ok {
+			return "ok"
+		}
+		return "FAIL"
+	}
+
+	fmt.Fprintf(w, "# Compatibility of %s\n\n", filepath.ToSlash(dir))
+	fmt.Fprintf(w, "Verified with %s on %s.\n\n", version, date.Format("January 02, 2006"))
+	fmt.Fprintf(w, "| Module | Directory | Build | Vet | Test | Skipped |\n")
+	fmt.Fprintf(w, "|:--|:--|:-:|:-:|:-:|:--|\n")
+	for _, r := range results {
+		rel, err := filepath.Rel(dir, r.Dir)
+		if err != nil {
+			rel = r.Dir
+		}
+		rel = filepath.ToSlash(rel)
+		if r.Error != "" {
+			fmt.Fprintf(w, "| %s | %s | FAIL | FAIL | FAIL | |\n", r.Module, rel)
+			continue
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s |\n", r.Module, rel,
+			status(r.Build), status(r.Vet), status(r.Test), strings.Join(r.Skipped, ", "))
+	}
+
+	for _, r := range results {
+		if r.ok() {
+			continue
+		}
+		fmt.Fprintf(w, "\n## %s\n", filepath.ToSlash(r.Dir))
+		if r.Error != "" {
+			fmt.Fprintf(w, "\n```\n%s\n```\n", strings.TrimSpace(r.Error))
+			continue
+		}
+		for _, s := range []struct {
+			name string
+			step
+		}{{"go build", r.Build}, {"go vet", r.Vet}, {"go test", r.Test}} {
+			if !s.Ok {
+				fmt.Fprintf(w, "\n%s:\n\n```\n%s\n```\n", s.name, strings.TrimSpace(s.Output))
+			}
+		}
+	}
+}
+
+// goCommand returns a go command that runs in dir. The module is not
+// part of any workspace, and go.mod and go.sum are never updated, so
+// that checking a module does not change it.
+func goCommand(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command(*goCmd, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=readonly")
+	return cmd
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequirements(t *testing.T) {
+	src := []byte(`// Copyright notice.
+
+//research:requires display, gpu
+
+package app
+
+// Not a directive: //research:requires cgo
+`)
+	got := requirements(src)
+	want := []string{"display", "gpu"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("requirements: got %v, want %v", got, want)
+	}
+}
+
+func TestSelectPackages(t *testing.T) {
+	pkgs := []*pkg{
+		{ImportPath: "x/thread"},
+		{ImportPath: "x/app", Deps: []string{"x/thread"}, requires: []string{"display"}},
+		{ImportPath: "x/cmd/app1", Deps: []string{"fmt", "x/app", "x/thread"}},
+	}
+
+	run, skipped := selectPackages(pkgs, map[string]bool{})
+	if want := []string{"x/thread"}; !reflect.DeepEqual(run, want) {
+		t.Fatalf("selectPackages: got %v, want %v", run, want)
+	}
+	if want := []string{"x/app (requires display)", "x/cmd/app1 (requires display)"}; !reflect.DeepEqual(skipped, want) {
+		t.Fatalf("selectPackages: got skipped %v, want %v", skipped, want)
+	}
+
+	run, skipped = selectPackages(pkgs, map[string]bool{"display": true})
+	if len(run) != 3 || len(skipped) != 0 {
+		t.Fatalf("selectPackages: got %v, %v, want all packages", run, skipped)
+	}
+}
+
+func TestCheckReadonly(t *testing.T) {
+	if _, err := exec.LookPath(*goCmd); err != nil {
+		t.Skipf("go command is not available: %v", err)
+	}
+	dir := t.TempDir()
+	gomod := "module m\n\ngo 1.18\n"
+	files := map[string]string{
+		"go.mod": gomod,
+		"m.go":   "package m\n\nimport _ \"golang.org/x/image/font\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := check(dir, nil)
+	if !strings.Contains(r.Error, "go.mod needs updating") {
+		t.Fatalf("check: got error %q, want go.mod needs updating", r.Error)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != gomod {
+		t.Fatalf("check: go.mod is changed to\n%s", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go.sum")); err == nil {
+		t.Fatalf("check: go.sum is created")
+	}
+}
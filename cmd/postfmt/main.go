@@ -0,0 +1,362 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+// Postfmt formats golang.design research posts, like gofmt does for
+// Go programs.
+//
+// Usage:
+//
+//	postfmt [flags] [path ...]
+//
+// The flags are:
+//
+//	-d
+//		Do not print reformatted posts to standard output.
+//		If a post's formatting is different than postfmt's, print diffs
+//		to standard output.
+//	-l
+//		Do not print reformatted posts to standard output.
+//		If a post's formatting is different from postfmt's, print its name
+//		to standard output.
+//	-w
+//		Do not print reformatted posts to standard output.
+//		If a post's formatting is different from postfmt's, overwrite it
+//		with postfmt's version.
+//
+// Given a directory, postfmt processes all .md files in it recursively.
+// Without an explicit path, it processes the standard input.
+//
+// A formatted post uses \n line endings, sorted front matter keys,
+// [at] obfuscated emails and code blocks without trailing whitespace.
+// Its references are sorted by their first citation, and references
+// that are never cited are kept at the end and reported. Citations of
+// the site-level bibliography (data/bibliography.yaml) do not need to
+// be defined in the post.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.design/x/research/internal/bib"
+	"golang.design/x/research/internal/post"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	list   = flag.Bool("l", false, "list files whose formatting differs from postfmt's")
+	write  = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	doDiff = flag.Bool("d", false, "display diffs instead of rewriting files")
+
+	exitCode = 0
+)
+
+func report(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	exitCode = 2
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: postfmt [flags] [path ...]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "error: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		if err := processFile("<standard input>", os.Stdin, os.Stdout); err != nil {
+			report(err)
+		}
+		os.Exit(exitCode)
+	}
+
+	for _, path := range flag.Args() {
+		switch fi, err := os.Stat(path); {
+		case err != nil:
+			report(err)
+		case fi.IsDir():
+			err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() && strings.HasSuffix(p, ".md") {
+					err = processFile(p, nil, os.Stdout)
+				}
+				if err != nil {
+					report(err)
+				}
+				return nil
+			})
+			if err != nil {
+				report(err)
+			}
+		default:
+			if err := processFile(path, nil, os.Stdout); err != nil {
+				report(err)
+			}
+		}
+	}
+	os.Exit(exitCode)
+}
+
+// processFile formats the post at the given filename. If in is nil,
+// the post is read from the file.
+func processFile(filename string, in io.Reader, out io.Writer) error {
+	if in == nil {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	src, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	bibl, err := bib.Load(filepath.Dir(filename))
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	res, warnings, err := format(src, bibl)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", filename, w)
+	}
+
+	if bytes.Equal(src, res) {
+		if !*list && !*write && !*doDiff {
+			_, err = out.Write(res)
+		}
+		return err
+	}
+
+	if *list {
+		fmt.Fprintln(out, filename)
+	}
+	if *write {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, res, fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if *doDiff {
+		data, err := diff(src, res, filename)
+		if err != nil {
+			return fmt.Errorf("computing diff: %s", err)
+		}
+		fmt.Fprintf(out, "diff -u %s.orig %s\n", filename, filename)
+		out.Write(data)
+	}
+	if !*list && !*write && !*doDiff {
+		_, err = out.Write(res)
+	}
+	return err
+}
+
+// format returns the canonical formatting of the given post, as well
+// as warnings about its references. Citations are resolved against the
+// given site-level bibliography.
+func format(src []byte, bibl bib.Bibliography) (res []byte, warnings []string, err error) {
+	res = bytes.ReplaceAll(src, []byte("\r\n"), []byte("\n"))
+	res = bytes.ReplaceAll(res, []byte("\r"), []byte("\n"))
+
+	res, err = formatFrontMatter(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	res = formatEmails(res)
+	res = formatCodeBlocks(res)
+	res, warnings = formatReferences(res, bibl)
+
+	res = append(bytes.TrimRight(res, "\n"), '\n')
+	return res, warnings, nil
+}
+
+// formatFrontMatter sorts the keys of the front matter, if any.
+func formatFrontMatter(src []byte) ([]byte, error) {
+	if !bytes.HasPrefix(src, []byte("---\n")) {
+		return src, nil
+	}
+	i := bytes.Index(src[4:], []byte("\n---\n"))
+	if i < 0 {
+		return nil, fmt.Errorf("front matter is not terminated by ---")
+	}
+	front, rest := src[4:4+i+1], src[4+i+1:]
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(front, &doc); err != nil {
+		return nil, fmt.Errorf("front matter: %v", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return src, nil
+	}
+	m := doc.Content[0]
+	pairs := make([][2]*yaml.Node, 0, len(m.Content)/2)
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{m.Content[i], m.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i][0].Value < pairs[j][0].Value
+	})
+	m.Content = m.Content[:0]
+	for _, p := range pairs {
+		m.Content = append(m.Content, p[0], p[1])
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(m); err != nil {
+		return nil, fmt.Errorf("front matter: %v", err)
+	}
+	buf.Write(rest)
+	return buf.Bytes(), nil
+}
+
+var reMailto = regexp.MustCompile(`\(mailto:[^)\s]*\)`)
+
+// formatEmails obfuscates the emails in mailto links using [at].
+func formatEmails(src []byte) []byte {
+	return reMailto.ReplaceAllFunc(src, func(b []byte) []byte {
+		return bytes.ReplaceAll(b, []byte("@"), []byte("[at]"))
+	})
+}
+
+// formatCodeBlocks removes trailing whitespace in fenced code blocks.
+func formatCodeBlocks(src []byte) []byte {
+	blocks := post.CodeBlocks(src)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		b := blocks[i]
+		lines := bytes.Split(src[b.Start:b.Stop], []byte("\n"))
+		for j := range lines {
+			lines[j] = bytes.TrimRight(lines[j], " \t")
+		}
+		code := bytes.Join(lines, []byte("\n"))
+		src = append(src[:b.Start:b.Start], append(code, src[b.Stop:]...)...)
+	}
+	return src
+}
+
+// formatReferences sorts the references by their first citation. The
+// references that are never cited are kept at the end in their
+// original order, followed by the Hugo shortcodes in the references,
+// such as {{% bibliography %}}. Unused references and citations that
+// are neither defined by the post nor by the site-level bibliography
+// are reported as warnings.
+func formatReferences(src []byte, bibl bib.Bibliography) ([]byte, []string) {
+	const heading = "## References\n"
+	i := bytes.Index(src, []byte(heading))
+	if i < 0 {
+		return src, nil
+	}
+	section := src[i+len(heading):]
+	shortcodes := []string{}
+	for _, l := range strings.Split(string(section), "\n") {
+		if strings.HasPrefix(l, "{{") {
+			shortcodes = append(shortcodes, l)
+		}
+	}
+	// Leave the section as it is if it contains more than references.
+	if head := bytes.TrimSpace(section); len(head) > 0 && !bytes.HasPrefix(head, []byte("[^")) && !bytes.HasPrefix(head, []byte("{{")) {
+		return src, []string{"references contain other content than reference definitions"}
+	}
+
+	refs, err := post.References(src)
+	if err != nil {
+		return src, nil
+	}
+	defined := map[string]post.Reference{}
+	for _, ref := range refs {
+		defined[ref.Key] = ref
+	}
+
+	warnings := []string{}
+	sorted := []post.Reference{}
+	for _, key := range post.Citations(src[:i]) {
+		ref, ok := defined[key]
+		if !ok {
+			if _, ok := bibl[key]; !ok {
+				warnings = append(warnings, fmt.Sprintf("undefined reference [^%s]", key))
+			}
+			continue
+		}
+		sorted = append(sorted, ref)
+		delete(defined, key)
+	}
+	for _, ref := range refs {
+		if _, ok := defined[ref.Key]; ok {
+			warnings = append(warnings, fmt.Sprintf("unused reference [^%s]", ref.Key))
+			sorted = append(sorted, ref)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write(src[:i+len(heading)])
+	buf.WriteString("\n")
+	for _, ref := range sorted {
+		fmt.Fprintf(&buf, "[^%s]: %s\n", ref.Key, ref.Text)
+	}
+	for _, sc := range shortcodes {
+		buf.WriteString(sc + "\n")
+	}
+	return buf.Bytes(), warnings
+}
+
+func diff(b1, b2 []byte, filename string) ([]byte, error) {
+	f1, err := writeTempFile("", "postfmt", b1)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f1)
+
+	f2, err := writeTempFile("", "postfmt", b2)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f2)
+
+	data, err := exec.Command("diff", "-u", "--label", filename+".orig", "--label", filename, f1, f2).CombinedOutput()
+	if len(data) > 0 {
+		// diff exits with a non-zero status when the files don't match.
+		// Ignore that failure as long as we get output.
+		err = nil
+	}
+	return data, err
+}
+
+func writeTempFile(dir, prefix string, data []byte) (string, error) {
+	file, err := os.CreateTemp(dir, prefix)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(data)
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.design/x/research/internal/bib"
+)
+
+func TestFormat(t *testing.T) {
+	src := "---\r\n" +
+		"title: Example\r\n" +
+		"date: 2020-09-30T09:02:20+01:00\r\n" +
+		"tags:\r\n" +
+		"    - Go\r\n" +
+		"---\r\n" +
+		"\r\n" +
+		"Author(s): [Jane Doe](mailto:jane@example.com)\r\n" +
+		"\r\n" +
+		"<!--abstract-->\r\n" +
+		"First[^b], then[^a] and[^missing][^shared].\r\n" +
+		"<!--more-->\r\n" +
+		"\r\n" +
+		"```go\r\n" +
+		"x := 1  \r\n" +
+		"```\r\n" +
+		"\r\n" +
+		"## References\r\n" +
+		"\r\n" +
+		"[^a]: Reference A.\r\n" +
+		"[^unused]: Unused reference.\r\n" +
+		"{{% bibliography %}}\r\n" +
+		"[^b]: Reference B.\r\n" +
+		"\r\n\r\n"
+
+	want := "---\n" +
+		"date: 2020-09-30T09:02:20+01:00\n" +
+		"tags:\n" +
+		"  - Go\n" +
+		"title: Example\n" +
+		"---\n" +
+		"\n" +
+		"Author(s): [Jane Doe](mailto:jane[at]example.com)\n" +
+		"\n" +
+		"<!--abstract-->\n" +
+		"First[^b], then[^a] and[^missing][^shared].\n" +
+		"<!--more-->\n" +
+		"\n" +
+		"```go\n" +
+		"x := 1\n" +
+		"```\n" +
+		"\n" +
+		"## References\n" +
+		"\n" +
+		"[^b]: Reference B.\n" +
+		"[^a]: Reference A.\n" +
+		"[^unused]: Unused reference.\n" +
+		"{{% bibliography %}}\n"
+
+	got, warnings, err := format([]byte(src), bib.Bibliography{"shared": "A shared reference."})
+	if err != nil {
+		t.Fatalf("format: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("format: got\n%s\nwant\n%s", got, want)
+	}
+	wantWarnings := []string{"undefined reference [^missing]", "unused reference [^unused]"}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Fatalf("format: got warnings %v, want %v", warnings, wantWarnings)
+	}
+
+	again, _, err := format(got, nil)
+	if err != nil {
+		t.Fatalf("format: %v", err)
+	}
+	if string(again) != string(got) {
+		t.Fatalf("format is not idempotent, got\n%s", again)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+// Postrun runs the Go programs in golang.design research posts and
+// verifies their recorded output.
+//
+// Usage:
+//
+//	postrun [flags] post.md ...
+//
+// A runnable code block is a fenced code block whose info string is
+// "go run". It contains a complete main package, and is followed by an
+// "output" code block that records the output of the program:
+//
+//	```go run
+//	package main
+//
+//	func main() { println("hello") }
+//	```
+//
+//	```output
+//	hello
+//	```
+//
+// If the info string names a directory, e.g.
+//
+//	```go run ../assets/generic-option/4generic
+//
+// the main package in that directory is run instead, and the block only
+// shows an excerpt of the program. The directory is relative to the post
+// and must be inside of a module, such as a module in content/assets.
+//
+// Each program is built and run in a temporary module, or a temporary
+// copy of its module, with module downloads disabled and a timeout.
+// The standard output and standard error of the program are recorded.
+// Postrun fails if a program fails or its output no longer matches the
+// recorded output. With -w, the recorded output is updated instead,
+// and a missing output block is inserted after the runnable block.
+//
+// The flags are:
+//
+//	-go string
+//		the go command used to build the programs (default "go")
+//	-timeout duration
+//		the maximum time to build and run each program (default 1m)
+//	-w
+//		write the output of the programs to the posts
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.design/x/research/internal/post"
+)
+
+var (
+	goCmd   = flag.String("go", "go", "the go command used to build the programs")
+	timeout = flag.Duration("timeout", time.Minute, "the maximum time to build and run each program")
+	write   = flag.Bool("w", false, "write the output of the programs to the posts")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: postrun [flags] post.md ...\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		if err := processFile(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// runMarker is the info string prefix of runnable code blocks.
+const runMarker = "go run"
+
+// isRunnable reports whether a code block with the given info string
+// is runnable.
+func isRunnable(info string) bool {
+	return info == runMarker || strings.HasPrefix(info, runMarker+" ")
+}
+
+// processFile runs all runnable code blocks of the post at path, and
+// either verifies or updates their recorded output.
+func processFile(path string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	res, errs := run(path, src)
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	if *write && !bytes.Equal(src, res) {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, res, fi.Mode().Perm())
+	}
+	return nil
+}
+
+// run runs all runnable code blocks of the given post, and returns the
+// post with updated output blocks. Failed programs, as well as programs
+// whose output do not match the recorded output if not writing, are
+// reported as errors.
+func run(path string, src []byte) ([]byte, []string) {
+	errs := []string{}
+	blocks := post.CodeBlocks(src)
+	res := append([]byte{}, src...)
+	line := func(offset int) int { return bytes.Count(src[:offset], []byte("\n")) + 1 }
+
+	// Edit the post from the end, such that the offsets of the earlier
+	// blocks remain valid.
+	for i := len(blocks) - 1; i >= 0; i-- {
+		b := blocks[i]
+		if !isRunnable(b.Info) {
+			continue
+		}
+
+		var out []byte
+		var err error
+		if dir := strings.TrimSpace(strings.TrimPrefix(b.Info, runMarker)); dir != "" {
+			out, err = runPackage(filepath.Join(filepath.Dir(path), dir))
+		} else {
+			out, err = runProgram(src[b.Start:b.Stop])
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s:%d: %v", path, line(b.Open), err))
+			continue
+		}
+		out = normalize(out)
+
+		// The output block is the next block, if there is only
+		// whitespace in between.
+		var output *post.CodeBlock
+		if i+1 < len(blocks) && blocks[i+1].Info == "output" &&
+			len(bytes.TrimSpace(src[b.Close:blocks[i+1].Open])) == 0 {
+			output = &blocks[i+1]
+		}
+
+		switch {
+		case output != nil && bytes.Equal(normalize(src[output.Start:output.Stop]), out):
+		case !*write && output == nil:
+			errs = append(errs, fmt.Sprintf("%s:%d: missing output block, got:\n%s", path, line(b.Open), out))
+		case !*write:
+			errs = append(errs, fmt.Sprintf("%s:%d: output does not match, got:\n%s\nwant:\n%s",
+				path, line(b.Open), out, normalize(src[output.Start:output.Stop])))
+		case output != nil:
+			res = append(res[:output.Start:output.Start], append(out, res[output.Stop:]...)...)
+		default:
+			block := append([]byte("\n```output\n"), out...)
+			block = append(block, "```\n"...)
+			res = append(res[:b.Close:b.Close], append(block, res[b.Close:]...)...)
+		}
+	}
+	return res, errs
+}
+
+// normalize removes trailing whitespace of each line and terminates
+// the output with a single newline, unless it is empty.
+func normalize(out []byte) []byte {
+	lines := bytes.Split(bytes.TrimRight(out, " \t\r\n"), []byte("\n"))
+	for i := range lines {
+		lines[i] = bytes.TrimRight(lines[i], " \t\r")
+	}
+	out = bytes.Join(lines, []byte("\n"))
+	if len(out) == 0 {
+		return out
+	}
+	return append(out, '\n')
+}
+
+// runProgram runs the given main package in a temporary module.
+func runProgram(code []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	tmp, err := os.MkdirTemp("", "postrun")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := os.WriteFile(filepath.Join(tmp, "main.go"), code, 0644); err != nil {
+		return nil, err
+	}
+	if out, err := goCommand(ctx, tmp, "mod", "init", "postrun").CombinedOutput(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("go mod init timed out after %v", *timeout)
+		}
+		return nil, fmt.Errorf("go mod init: %v\n%s", err, out)
+	}
+	return buildAndRun(ctx, tmp, ".")
+}
+
+// runPackage runs the main package in dir in a temporary copy of the
+// module that contains dir.
+func runPackage(dir string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	root := dir
+	for {
+		if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
+			break
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return nil, fmt.Errorf("%s is not inside of a module", dir)
+		}
+		root = parent
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "postrun")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		r, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(tmp, r), 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(tmp, r), b, 0644)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot copy module %s: %v", root, err)
+	}
+	return buildAndRun(ctx, tmp, "./"+filepath.ToSlash(rel))
+}
+
+// buildAndRun builds the main package pkg in the module at dir, and
+// runs the program, until the context is done.
+func buildAndRun(ctx context.Context, dir, pkg string) ([]byte, error) {
+	bin := filepath.Join(dir, "postrun.exe")
+	build := goCommand(ctx, dir, "build", "-o", bin, pkg)
+	if out, err := build.CombinedOutput(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("go build timed out after %v\n%s", *timeout, truncate(out))
+		}
+		return nil, fmt.Errorf("go build: %v\n%s", err, out)
+	}
+
+	cmd := exec.CommandContext(ctx, bin)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("program timed out after %v\n%s", *timeout, truncate(out))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("program failed: %v\n%s", err, truncate(out))
+	}
+	return out, nil
+}
+
+// maxErrOutput is the maximum output of a failed program that is
+// included in an error, such that a goroutine dump does not flood the
+// terminal.
+const maxErrOutput = 4 << 10
+
+// truncate returns the first maxErrOutput bytes of the output.
+func truncate(out []byte) []byte {
+	if len(out) <= maxErrOutput {
+		return out
+	}
+	return append(out[:maxErrOutput:maxErrOutput], "\n... (output truncated)"...)
+}
+
+// goCommand returns a go command that runs in dir until the context is
+// done. Module downloads are disabled, and the module is not part of
+// any workspace.
+func goCommand(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, *goCmd, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOPROXY=off", "GOWORK=off", "GOFLAGS=-mod=mod")
+	return cmd
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+const hello = "```go run\n" +
+	"package main\n" +
+	"\n" +
+	"import \"fmt\"\n" +
+	"\n" +
+	"func main() { fmt.Println(\"hello, world  \") }\n" +
+	"```\n"
+
+func TestRun(t *testing.T) {
+	if _, err := exec.LookPath(*goCmd); err != nil {
+		t.Skipf("go command is not available: %v", err)
+	}
+	defer func(w bool) { *write = w }(*write)
+
+	want := "Text.\n\n" + hello + "\n```output\nhello, world\n```\n\nMore text.\n"
+
+	*write = false
+	if _, errs := run("post.md", []byte(want)); len(errs) != 0 {
+		t.Fatalf("run: got errors %v", errs)
+	}
+	stale := strings.Replace(want, "hello, world\n```", "bye\n```", 1)
+	if _, errs := run("post.md", []byte(stale)); len(errs) != 1 {
+		t.Fatalf("run: got errors %v, want a mismatch", errs)
+	}
+
+	*write = true
+	if got, errs := run("post.md", []byte(stale)); len(errs) != 0 || string(got) != want {
+		t.Fatalf("run: got %q, %v, want %q", got, errs, want)
+	}
+	missing := "Text.\n\n" + hello + "\nMore text.\n"
+	if got, errs := run("post.md", []byte(missing)); len(errs) != 0 || string(got) != want {
+		t.Fatalf("run: got %q, %v, want %q", got, errs, want)
+	}
+
+	// Each of several output blocks is updated.
+	hello2 := strings.Replace(hello, "hello, world", "hello, gophers", 1)
+	want = "Text.\n\n" + hello + "\n```output\nhello, world\n```\n\n" +
+		hello2 + "\n```output\nhello, gophers\n```\n"
+	stale = strings.Replace(want, "hello, world\n```", "stale\n```", 1)
+	stale = strings.Replace(stale, "hello, gophers\n```", "stale\n```", 1)
+	if got, errs := run("post.md", []byte(stale)); len(errs) != 0 || string(got) != want {
+		t.Fatalf("run: got %q, %v, want %q", got, errs, want)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	if _, err := exec.LookPath(*goCmd); err != nil {
+		t.Skipf("go command is not available: %v", err)
+	}
+	defer func(d time.Duration) { *timeout = d }(*timeout)
+
+	// The go commands are cancelled as well as the program.
+	*timeout = time.Millisecond
+	_, errs := run("post.md", []byte(hello))
+	if len(errs) != 1 || !strings.Contains(errs[0], "timed out") {
+		t.Fatalf("run: got errors %v, want a timeout", errs)
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+// Postvet examines golang.design research posts and reports figures
+// that are not accessible, like go vet does for Go programs.
+//
+// Usage:
+//
+//	postvet [path ...]
+//
+// Given a directory, postvet examines all .md files in it recursively.
+// Without an explicit path, it examines content/posts.
+//
+// Every figure needs an alternative text for screen readers, which is
+// also attached to the figure in the PDF by pdfgen:
+//
+//	![The alternative text](../assets/post/figure.png "The caption")
+//
+// The alternative text of a benchchart is its alt or title parameter,
+// and an <img> of raw HTML needs an alt attribute. A missing
+// alternative text is an error.
+//
+// Postvet also looks at the pixels of PNG, JPEG and GIF figures. A
+// screenshot whose content barely contrasts with its background (less
+// than 3:1, see WCAG 2.1 non-text contrast), or that is dense with
+// text, is hard to read for many readers, so it should be described by
+// an alternative text of at least 12 words or by a caption. Such
+// figures are reported as warnings.
+//
+// Postvet exits with status 1 if there are errors, and 2 if a post
+// cannot be examined.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.design/x/research/internal/benchchart"
+	"golang.design/x/research/internal/post"
+)
+
+const (
+	// minContrast is the minimum contrast ratio between the content of
+	// a figure and its background, see WCAG 2.1 success criterion 1.4.11.
+	minContrast = 3
+	// maxEdges is the fraction of sharp edges between neighboring
+	// pixels above which a figure is dense with text.
+	maxEdges = 0.012
+	// minWords is the minimum number of words of an alternative text
+	// that describes a figure which is hard to read.
+	minWords = 12
+)
+
+var exitCode = 0
+
+func report(err error) {
+	fmt.Fprintf(os.Stderr, "postvet: %v\n", err)
+	exitCode = 2
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: postvet [path ...]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"content/posts"}
+	}
+	errs := false
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || filepath.Ext(path) != ".md" {
+				return nil
+			}
+			diags, err := vet(path)
+			if err != nil {
+				report(err)
+				return nil
+			}
+			for _, d := range diags {
+				fmt.Println(d)
+				errs = errs || !d.warning
+			}
+			return nil
+		})
+		if err != nil {
+			report(err)
+		}
+	}
+	if exitCode == 0 && errs {
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// A diagnostic is a problem of a post.
+type diagnostic struct {
+	path    string
+	line    int
+	warning bool
+	msg     string
+}
+
+func (d diagnostic) String() string {
+	if d.warning {
+		return fmt.Sprintf("%s:%d: warning: %s", d.path, d.line, d.msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", d.path, d.line, d.msg)
+}
+
+var (
+	reImgTag = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+	reAlt    = regexp.MustCompile(`(?i)\salt\s*=`)
+)
+
+// vet examines the figures of the post at path.
+func vet(path string) ([]diagnostic, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	diags := []diagnostic{}
+	at := func(off int, warning bool, format string, args ...any) {
+		line := bytes.Count(b[:off], []byte("\n")) + 1
+		diags = append(diags, diagnostic{path, line, warning, fmt.Sprintf(format, args...)})
+	}
+
+	for _, img := range post.Images(b) {
+		if strings.TrimSpace(img.Alt) == "" {
+			at(img.Start, false, "figure %s has no alternative text", img.Src)
+		}
+		if described(img) || strings.Contains(img.Src, "://") {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(img.Src)) {
+		case ".png", ".jpg", ".jpeg", ".gif":
+		default:
+			continue
+		}
+		m, err := loadImage(filepath.Join(filepath.Dir(path), img.Src))
+		if err != nil {
+			at(img.Start, false, "%v", err)
+			continue
+		}
+		contrast, edges := analyze(m)
+		if contrast < minContrast {
+			at(img.Start, true, "figure %s has a low contrast of %.1f:1, describe it in a caption or an alternative text of at least %d words", img.Src, contrast, minWords)
+		}
+		if edges > maxEdges {
+			at(img.Start, true, "figure %s is dense with text, describe it in a caption or an alternative text of at least %d words", img.Src, minWords)
+		}
+	}
+
+	for _, sc := range benchchart.Shortcodes(b) {
+		if sc.Params["alt"] == "" && sc.Params["title"] == "" {
+			at(bytes.Index(b, []byte(sc.Text)), false, "benchchart %s has neither an alt nor a title", sc.Params["name"])
+		}
+	}
+	for _, loc := range reImgTag.FindAllIndex(b, -1) {
+		if !reAlt.Match(b[loc[0]:loc[1]]) {
+			at(loc[0], false, "<img> has no alt attribute")
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].line < diags[j].line })
+	return diags, nil
+}
+
+// described reports whether the figure is described by a caption or a
+// long alternative text.
+func described(img post.Image) bool {
+	return img.Title != "" || len(strings.Fields(img.Alt)) >= minWords
+}
+
+func loadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open figure: %v", err)
+	}
+	defer f.Close()
+	m, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode figure %s: %v", path, err)
+	}
+	return m, nil
+}
+
+// analyze returns the contrast of the content of m with its background,
+// and the fraction of sharp edges between horizontally neighboring
+// pixels, which is high for text.
+//
+// The background is the most frequent luminance of m. The contrast is
+// the 90th percentile of the contrast ratios of the pixels that differ
+// from the background, so that a few dark pixels, e.g. a thin border,
+// do not make up for a figure that is pale otherwise. An edge is sharp
+// if its contrast ratio is at least 3:1.
+func analyze(m image.Image) (contrast, edges float64) {
+	r := m.Bounds()
+	w, h := r.Dx(), r.Dy()
+	if w == 0 || h == 0 {
+		return 1, 0
+	}
+	lum := make([]float64, w*h)
+	var hist [101]int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			l := luminance(m.At(r.Min.X+x, r.Min.Y+y).RGBA())
+			lum[y*w+x] = l
+			hist[int(l*100)]++
+		}
+	}
+	mode := 0
+	for i, n := range hist {
+		if n > hist[mode] {
+			mode = i
+		}
+	}
+	bg := (float64(mode) + 0.5) / 100
+
+	ratios := []float64{}
+	sharp := 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			l := lum[y*w+x]
+			if c := contrastRatio(l, bg); c > 1.25 {
+				ratios = append(ratios, c)
+			}
+			if x+1 < w && contrastRatio(l, lum[y*w+x+1]) >= 3 {
+				sharp++
+			}
+		}
+	}
+	contrast = 1
+	if len(ratios) > 0 {
+		sort.Float64s(ratios)
+		contrast = ratios[len(ratios)*9/10]
+	}
+	if w > 1 {
+		edges = float64(sharp) / float64((w-1)*h)
+	}
+	return contrast, edges
+}
+
+// luminance returns the relative luminance of a color on a white
+// background, see https://www.w3.org/TR/WCAG21/#dfn-relative-luminance.
+func luminance(r, g, b, a uint32) float64 {
+	channel := func(v uint32) float64 {
+		// The color is alpha-premultiplied.
+		s := (float64(v) + float64(0xffff-a)) / 0xffff
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(r) + 0.7152*channel(g) + 0.0722*channel(b)
+}
+
+// contrastRatio returns the contrast ratio of two relative luminances,
+// see https://www.w3.org/TR/WCAG21/#dfn-contrast-ratio.
+func contrastRatio(l1, l2 float64) float64 {
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05)
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVet(t *testing.T) {
+	dir := t.TempDir()
+	figures := map[string]func(x, y int) color.Color{
+		// a dark block on white
+		"plain.png": func(x, y int) color.Color {
+			if x >= 50 && x < 150 && y >= 50 && y < 150 {
+				return color.Black
+			}
+			return color.White
+		},
+		// a pale block on white, like the bars of go tool trace
+		"pale.png": func(x, y int) color.Color {
+			if x >= 50 && x < 150 && y >= 50 && y < 150 {
+				return color.RGBA{0xc0, 0xd8, 0xf0, 0xff}
+			}
+			return color.White
+		},
+		// thin dark strokes on white, like text
+		"text.png": func(x, y int) color.Color {
+			if x%4 == 0 && y%8 < 6 {
+				return color.Black
+			}
+			return color.White
+		},
+	}
+	for name, at := range figures {
+		m := image.NewRGBA(image.Rect(0, 0, 200, 200))
+		for y := 0; y < 200; y++ {
+			for x := 0; x < 200; x++ {
+				m.Set(x, y, at(x, y))
+			}
+		}
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(f, m); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+
+	path := filepath.Join(dir, "post.md")
+	src := `![A black square](plain.png)
+![](plain.png)
+![A pale square](pale.png)
+![A pale square](pale.png "Described by the caption")
+![A page of text](text.png)
+![](missing.png)
+
+{{< benchchart name="chart" files="a.txt" >}}
+{{< benchchart name="titled" files="a.txt" title="A chart" >}}
+
+{{< rawhtml >}}<img src="plain.png"><img src="plain.png" alt="">{{< /rawhtml >}}
+
+` + "```md\n![](plain.png)\n```\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, errMissing := os.Open(filepath.Join(dir, "missing.png"))
+	diags, err := vet(path)
+	if err != nil {
+		t.Fatalf("vet: %v", err)
+	}
+	got := []string{}
+	for _, d := range diags {
+		got = append(got, d.String()[len(dir)+1:])
+	}
+	want := []string{
+		"post.md:2: figure plain.png has no alternative text",
+		"post.md:3: warning: figure pale.png has a low contrast of 1.5:1, describe it in a caption or an alternative text of at least 12 words",
+		"post.md:5: warning: figure text.png is dense with text, describe it in a caption or an alternative text of at least 12 words",
+		"post.md:6: figure missing.png has no alternative text",
+		"post.md:6: cannot open figure: " + errMissing.Error(),
+		"post.md:8: benchchart chart has neither an alt nor a title",
+		"post.md:11: <img> has no alt attribute",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("vet: got\n%q\nwant\n%q", got, want)
+	}
+}
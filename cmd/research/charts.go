@@ -0,0 +1,108 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"golang.design/x/research/internal/benchchart"
+	"golang.design/x/research/internal/site"
+)
+
+const chartsUsage = `usage: research charts [-check]
+
+Charts draws the charts that the posts in content/posts embed with the
+benchchart shortcode from the benchmark files in content/assets, and writes
+them to ` + benchchart.Dir + `/<name>.svg. Run it after adding a shortcode or
+updating a benchmark file.
+
+The flags are:
+`
+
+func runCharts(args []string) error {
+	fs := flag.NewFlagSet("charts", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), chartsUsage)
+		fs.PrintDefaults()
+	}
+	check := fs.Bool("check", false, "only check that the charts are up to date")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+
+	c, err := site.Load(".")
+	if err != nil {
+		return err
+	}
+	charts, err := benchCharts(c.Root)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(c.Root, benchchart.Dir)
+	for name, b := range charts {
+		path := filepath.Join(dir, name+".svg")
+		old, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if bytes.Equal(old, b) {
+			continue
+		}
+		if *check {
+			return fmt.Errorf("%v/%v.svg is out of date, run research charts", benchchart.Dir, name)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, b, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "wrote %v/%v.svg\n", benchchart.Dir, name)
+	}
+	return nil
+}
+
+// benchCharts returns the SVG charts of all benchchart shortcodes in the
+// posts of the site at root by name.
+func benchCharts(root string) (map[string][]byte, error) {
+	posts, err := filepath.Glob(filepath.Join(root, "content", "posts", "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	charts := map[string][]byte{}
+	params := map[string]map[string]string{}
+	for _, p := range posts {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, sc := range benchchart.Shortcodes(b) {
+			name := sc.Params["name"]
+			if prev, ok := params[name]; ok {
+				if !reflect.DeepEqual(prev, sc.Params) {
+					return nil, fmt.Errorf("%v: benchchart %v is defined differently elsewhere", filepath.Base(p), name)
+				}
+				continue
+			}
+			chart, err := benchchart.Build(root, sc)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", filepath.Base(p), err)
+			}
+			params[name] = sc.Params
+			charts[name] = chart.SVG()
+		}
+	}
+	return charts, nil
+}
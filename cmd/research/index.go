@@ -0,0 +1,272 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"golang.design/x/research/internal/post"
+	"golang.design/x/research/internal/site"
+	"golang.design/x/research/internal/xref"
+)
+
+const indexUsage = `usage: research index [-check]
+
+Index reads the posts in content/posts the way pdfgen does, and writes an
+index of the articles with their authors, abstracts and PDFs to ` + indexPath + `
+and an Atom feed with their full abstracts to ` + feedPath + `. The citation
+partial of the theme reads the index for the citation_* meta tags of Google
+Scholar. Draft posts are left out. Run it after adding or updating a post.
+
+The flags are:
+`
+
+// The paths of the generated files relative to the site root.
+const (
+	indexPath = "static/index.json"
+	feedPath  = "static/atom.xml"
+)
+
+func runIndex(args []string) error {
+	fs := flag.NewFlagSet("index", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), indexUsage)
+		fs.PrintDefaults()
+	}
+	check := fs.Bool("check", false, "only check that the index and the feed are up to date")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+
+	c, err := site.Load(".")
+	if err != nil {
+		return err
+	}
+	articles, err := loadArticles(c)
+	if err != nil {
+		return err
+	}
+	index, err := articleIndex(c, articles)
+	if err != nil {
+		return err
+	}
+	feed, err := atomFeed(c, articles)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range []struct {
+		path string
+		b    []byte
+	}{{indexPath, index}, {feedPath, feed}} {
+		path := filepath.Join(c.Root, f.path)
+		old, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if bytes.Equal(old, f.b) {
+			continue
+		}
+		if *check {
+			return fmt.Errorf("%v is out of date, run research index", f.path)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, f.b, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "wrote %v\n", f.path)
+	}
+	return nil
+}
+
+// An article is an entry of the index.
+type article struct {
+	Title    string    `json:"title"`
+	Slug     string    `json:"slug"`
+	URL      string    `json:"url"`
+	Authors  []string  `json:"authors"`
+	Tags     []string  `json:"tags"`
+	Date     time.Time `json:"date"`
+	Abstract string    `json:"abstract"`         // in plain text
+	PDF      string    `json:"pdf,omitempty"`    // the URL of the PDF
+	Assets   string    `json:"assets,omitempty"` // the asset directory relative to the site root
+
+	abstractHTML string
+}
+
+// loadArticles loads the posts of the site that are not drafts, the
+// newest first.
+func loadArticles(c *site.Config) ([]*article, error) {
+	posts, err := xref.Load(c)
+	if err != nil {
+		return nil, err
+	}
+	articles := []*article{}
+	for _, p := range posts {
+		a, err := loadArticle(c, p, posts)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", filepath.Base(p.Path), err)
+		}
+		articles = append(articles, a)
+	}
+	sort.Slice(articles, func(i, j int) bool {
+		if !articles[i].Date.Equal(articles[j].Date) {
+			return articles[i].Date.After(articles[j].Date)
+		}
+		return articles[i].Slug < articles[j].Slug
+	})
+	return articles, nil
+}
+
+// loadArticle loads the abstract, the PDF and the assets of a post. The
+// links to other posts in the abstract are resolved against posts.
+func loadArticle(c *site.Config, p *xref.Article, posts map[string]*xref.Article) (*article, error) {
+	b, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = xref.Cite(b, posts); err != nil {
+		return nil, err
+	}
+	abstract, err := post.Abstract(b)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := post.Markdown.Convert([]byte(post.ReplaceCitations(abstract, "")), &buf); err != nil {
+		return nil, err
+	}
+
+	a := &article{
+		Title:        p.Title,
+		Slug:         p.Slug,
+		URL:          p.URL,
+		Authors:      p.Authors,
+		Tags:         p.Tags,
+		Date:         p.Date,
+		Abstract:     post.PlainText(abstract),
+		abstractHTML: buf.String(),
+	}
+	// pdfgen writes content/<name>.pdf, which Hugo publishes at the
+	// root of the site, and attaches content/assets/<slug>.
+	if _, err := os.Stat(filepath.Join(c.Root, "content", p.Name+".pdf")); err == nil {
+		a.PDF = c.BaseURL + "/" + p.Name + ".pdf"
+	}
+	if fi, err := os.Stat(filepath.Join(c.Root, "content", "assets", p.Slug)); err == nil && fi.IsDir() {
+		a.Assets = "content/assets/" + p.Slug
+	}
+	return a, nil
+}
+
+// articleIndex returns the JSON index of the articles.
+func articleIndex(c *site.Config, articles []*article) ([]byte, error) {
+	b, err := json.MarshalIndent(struct {
+		Title    string     `json:"title"`
+		URL      string     `json:"url"`
+		Articles []*article `json:"articles"`
+	}{c.Title, c.BaseURL, articles}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// The elements of an Atom feed, see RFC 4287.
+type (
+	feed struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Title   string   `xml:"title"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Links   []link   `xml:"link"`
+		Entries []entry  `xml:"entry"`
+	}
+	entry struct {
+		Title      string     `xml:"title"`
+		ID         string     `xml:"id"`
+		Published  string     `xml:"published"`
+		Updated    string     `xml:"updated"`
+		Links      []link     `xml:"link"`
+		Authors    []person   `xml:"author"`
+		Categories []category `xml:"category"`
+		Summary    content    `xml:"summary"`
+	}
+	link struct {
+		Rel  string `xml:"rel,attr,omitempty"`
+		Type string `xml:"type,attr,omitempty"`
+		Href string `xml:"href,attr"`
+	}
+	person struct {
+		Name string `xml:"name"`
+	}
+	category struct {
+		Term string `xml:"term,attr"`
+	}
+	content struct {
+		Type string `xml:"type,attr"`
+		Body string `xml:",chardata"`
+	}
+)
+
+// atomFeed returns the Atom feed of the articles. The feed is updated
+// when the newest article is published, so that it only changes with
+// the posts.
+func atomFeed(c *site.Config, articles []*article) ([]byte, error) {
+	f := feed{
+		Title: c.Title,
+		ID:    c.BaseURL + "/",
+		Links: []link{
+			{Rel: "self", Type: "application/atom+xml", Href: c.BaseURL + "/atom.xml"},
+			{Rel: "alternate", Type: "text/html", Href: c.BaseURL + "/"},
+		},
+	}
+	if len(articles) > 0 {
+		f.Updated = articles[0].Date.Format(time.RFC3339)
+	}
+	for _, a := range articles {
+		e := entry{
+			Title:     a.Title,
+			ID:        a.URL,
+			Published: a.Date.Format(time.RFC3339),
+			Updated:   a.Date.Format(time.RFC3339),
+			Links:     []link{{Rel: "alternate", Type: "text/html", Href: a.URL}},
+			Summary:   content{Type: "html", Body: a.abstractHTML},
+		}
+		if a.PDF != "" {
+			e.Links = append(e.Links, link{Rel: "alternate", Type: "application/pdf", Href: a.PDF})
+		}
+		for _, name := range a.Authors {
+			e.Authors = append(e.Authors, person{name})
+		}
+		for _, t := range a.Tags {
+			e.Categories = append(e.Categories, category{t})
+		}
+		f.Entries = append(f.Entries, e)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
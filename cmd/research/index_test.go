@@ -0,0 +1,123 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const indexPost = `---
+date: 2021-08-09T09:02:42+02:00
+slug: /%s
+tags:
+  - Go
+title: %s
+draft: %v
+---
+
+Author(s): [Jane Doe](mailto:jane[at]example.com), [John Doe](mailto:john[at]example.com)
+
+<!--abstract-->
+An abstract with a [link](https://go.dev)[^doe2021].
+<!--more-->
+
+## Introduction
+`
+
+func TestIndex(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"config.toml":               "baseURL = \"https://golang.design/research\"\ntitle = \"golang.design/research\"\n[permalinks]\n  posts = \"/:slug\"\n",
+		"content/posts/old.md":      strings.Replace(fmt.Sprintf(indexPost, "old", "OLD", false), "2021", "2020", 1),
+		"content/posts/2021-new.md": fmt.Sprintf(indexPost, "new", "NEW", false),
+		"content/posts/draft.md":    fmt.Sprintf(indexPost, "draft", "DRAFT", true),
+		"content/2021-new.pdf":      "%PDF",
+		"content/assets/new/go.mod": "module new\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := runIndex([]string{"-check"}); err == nil {
+		t.Fatalf("runIndex: expect an error for a missing index")
+	}
+	if err := runIndex(nil); err != nil {
+		t.Fatalf("runIndex: %v", err)
+	}
+	if err := runIndex([]string{"-check"}); err != nil {
+		t.Fatalf("runIndex: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(root, indexPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index struct {
+		Articles []*article
+	}
+	if err := json.Unmarshal(b, &index); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	if len(index.Articles) != 2 {
+		t.Fatalf("index: got %d articles, want 2", len(index.Articles))
+	}
+	a := index.Articles[0]
+	want := &article{
+		Title:    "NEW",
+		Slug:     "new",
+		URL:      "https://golang.design/research/new",
+		Authors:  []string{"Jane Doe", "John Doe"},
+		Tags:     []string{"Go"},
+		Date:     a.Date,
+		Abstract: "An abstract with a link.",
+		PDF:      "https://golang.design/research/2021-new.pdf",
+		Assets:   "content/assets/new",
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Fatalf("index: got %+v, want %+v", a, want)
+	}
+	if old := index.Articles[1]; old.Slug != "old" || old.PDF != "" || old.Assets != "" {
+		t.Fatalf("index: got %+v", old)
+	}
+
+	b, err = os.ReadFile(filepath.Join(root, feedPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f feed
+	if err := xml.Unmarshal(b, &f); err != nil {
+		t.Fatalf("feed: %v", err)
+	}
+	if len(f.Entries) != 2 || f.Updated != "2021-08-09T09:02:42+02:00" {
+		t.Fatalf("feed: got %+v", f)
+	}
+	e := f.Entries[0]
+	if e.ID != want.URL || len(e.Links) != 2 || e.Links[1].Href != want.PDF || len(e.Authors) != 2 {
+		t.Fatalf("feed: got entry %+v", e)
+	}
+	if e.Summary.Type != "html" || e.Summary.Body != "<p>An abstract with a <a href=\"https://go.dev\">link</a>.</p>\n" {
+		t.Fatalf("feed: got summary %q", e.Summary.Body)
+	}
+}
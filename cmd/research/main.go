@@ -0,0 +1,66 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+// Research is a tool for managing golang.design research posts.
+//
+// Usage:
+//
+//	research <command> [arguments]
+//
+// The commands are:
+//
+//	charts   draw the charts of benchmark files that the posts embed
+//	index    write the index and the Atom feed of the articles
+//	new      create a post and its companion asset module
+//	tables   summarize the benchmark files that the posts embed
+//
+// Use "research <command> -h" for more information about a command.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// A command is a subcommand of research.
+type command struct {
+	name  string
+	short string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"charts", "draw the charts of benchmark files that the posts embed", runCharts},
+	{"index", "write the index and the Atom feed of the articles", runIndex},
+	{"new", "create a post and its companion asset module", runNew},
+	{"tables", "summarize the benchmark files that the posts embed", runTables},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "research is a tool for managing golang.design research posts.\n\n")
+	fmt.Fprintf(os.Stderr, "usage: research <command> [arguments]\n\nThe commands are:\n\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-8s %s\n", c.name, c.short)
+	}
+	fmt.Fprintf(os.Stderr, "\nUse \"research <command> -h\" for more information about a command.\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name != os.Args[1] {
+			continue
+		}
+		if err := c.run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "research %s: %v\n", c.name, err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "research: unknown command %q\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
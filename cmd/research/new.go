@@ -0,0 +1,290 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"golang.design/x/research/internal/site"
+	"gopkg.in/yaml.v3"
+)
+
+const newUsage = `usage: research new [-author id,...] [-title title] [-tags tag,...] [-assets=false] slug
+
+New creates the post content/posts/<slug>.md, which follows the conventions
+of pdfgen, and its companion asset module content/assets/<slug> with a
+go.mod, a benchmark skeleton and a Makefile. The authors are looked up by
+their IDs in data/authors.yaml. If the file contains only one author, the
+author may be omitted.
+
+The flags are:
+`
+
+// authorsPath is the path of the authors file relative to the site root.
+const authorsPath = "data/authors.yaml"
+
+// An author is an entry of the authors file.
+type author struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+}
+
+var reSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func runNew(args []string) error {
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), newUsage)
+		fs.PrintDefaults()
+	}
+	ids := fs.String("author", "", "comma-separated `list` of author IDs in "+authorsPath)
+	title := fs.String("title", "", "the title of the post (default derived from the slug)")
+	tags := fs.String("tags", "Go", "comma-separated `list` of tags")
+	assets := fs.Bool("assets", true, "create the companion asset module")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expect exactly one slug")
+	}
+	slug := fs.Arg(0)
+	if !reSlug.MatchString(slug) {
+		return fmt.Errorf("invalid slug %q, expect lower case words separated by hyphens", slug)
+	}
+
+	c, err := site.Load(".")
+	if err != nil {
+		return err
+	}
+	authors, err := lookupAuthors(c.Root, *ids)
+	if err != nil {
+		return err
+	}
+
+	p := newPost{
+		Slug:    slug,
+		Title:   *title,
+		Date:    time.Now(),
+		Authors: authors,
+	}
+	if p.Title == "" {
+		p.Title = titleOf(slug)
+	}
+	for _, t := range strings.Split(*tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			p.Tags = append(p.Tags, t)
+		}
+	}
+	p.Permalink = c.Permalink(site.Page{
+		Section:  "posts",
+		Filename: slug,
+		Slug:     "/" + slug,
+		Title:    p.Title,
+		Date:     p.Date,
+	})
+
+	files := map[string]*template.Template{
+		filepath.Join("content", "posts", slug+".md"): postTmpl,
+	}
+	if *assets {
+		dir := filepath.Join("content", "assets", slug)
+		files[filepath.Join(dir, "go.mod")] = goModTmpl
+		files[filepath.Join(dir, "bench_test.go")] = benchTmpl
+		files[filepath.Join(dir, "Makefile")] = makefileTmpl
+	}
+	return writeFiles(c.Root, files, p)
+}
+
+// A newPost is the data of the templates of a new post.
+type newPost struct {
+	Slug      string
+	Title     string
+	Date      time.Time
+	Tags      []string
+	Authors   []author
+	Permalink string
+}
+
+// Package returns the package name of the benchmarks, which is
+// prefixed by bench if the slug starts with a digit.
+func (p newPost) Package() string {
+	name := strings.ReplaceAll(p.Slug, "-", "")
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "bench" + name
+	}
+	return name + "_test"
+}
+
+// GoVersion returns the language version of the asset module, i.e. the
+// version of the running Go release, e.g. 1.18.
+func (p newPost) GoVersion() string {
+	v := strings.TrimPrefix(runtime.Version(), "go")
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 || strings.ContainsAny(parts[1], " -") {
+		return "1.18" // a development version
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// lookupAuthors returns the authors with the given comma-separated IDs
+// from the authors file of the site in root.
+func lookupAuthors(root, ids string) ([]author, error) {
+	b, err := os.ReadFile(filepath.Join(root, authorsPath))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read authors: %w", err)
+	}
+	all := map[string]author{}
+	if err := yaml.Unmarshal(b, &all); err != nil {
+		return nil, fmt.Errorf("%v: %w", authorsPath, err)
+	}
+	known := make([]string, 0, len(all))
+	for id := range all {
+		known = append(known, id)
+	}
+	sort.Strings(known)
+
+	if ids == "" {
+		if len(known) != 1 {
+			return nil, fmt.Errorf("missing -author, known authors are %v", strings.Join(known, ", "))
+		}
+		ids = known[0]
+	}
+	authors := []author{}
+	for _, id := range strings.Split(ids, ",") {
+		a, ok := all[strings.TrimSpace(id)]
+		if !ok {
+			return nil, fmt.Errorf("unknown author %q, add it to %v (known authors are %v)",
+				id, authorsPath, strings.Join(known, ", "))
+		}
+		if a.Name == "" || a.Email == "" {
+			return nil, fmt.Errorf("author %q in %v needs both a name and an email", id, authorsPath)
+		}
+		authors = append(authors, a)
+	}
+	return authors, nil
+}
+
+// titleOf derives a title from a slug, e.g. bench-time to Bench Time.
+func titleOf(slug string) string {
+	words := strings.Split(slug, "-")
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// writeFiles executes the templates to the files in root. It fails
+// without writing anything if any of the files exists.
+func writeFiles(root string, files map[string]*template.Template, p newPost) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			return fmt.Errorf("%v already exists", name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var buf bytes.Buffer
+		if err := files[name].Execute(&buf, p); err != nil {
+			return err
+		}
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "created %v\n", name)
+	}
+	return nil
+}
+
+var funcs = template.FuncMap{
+	"obfuscate": func(email string) string { return strings.Replace(email, "@", "[at]", 1) },
+	// yaml quotes a string as a YAML scalar if needed, e.g. a title
+	// with a colon.
+	"yaml": func(s string) (string, error) {
+		b, err := yaml.Marshal(s)
+		return strings.TrimSuffix(string(b), "\n"), err
+	},
+}
+
+// The front matter keys are sorted as postfmt does.
+var postTmpl = template.Must(template.New("post").Funcs(funcs).Parse(`---
+date: {{.Date.Format "2006-01-02T15:04:05-07:00"}}
+draft: true
+slug: /{{.Slug}}
+tags:
+{{- range .Tags}}
+  - {{yaml .}}
+{{- end}}
+title: {{yaml .Title}}
+---
+
+Author(s): {{range $i, $a := .Authors}}{{if $i}}, {{end}}[{{$a.Name}}](mailto:{{obfuscate $a.Email}}){{end}}
+
+Permalink: {{.Permalink}}
+
+<!--abstract-->
+TODO: summarize the post in a few sentences.
+<!--more-->
+
+## Introduction
+
+TODO: the companion code of this post is in content/assets/{{.Slug}}.
+
+## Conclusion
+
+## References
+
+{{"{{% bibliography %}}"}}
+`))
+
+var goModTmpl = template.Must(template.New("go.mod").Parse(`module {{.Slug}}
+
+go {{.GoVersion}}
+`))
+
+var benchTmpl = template.Must(template.New("bench_test.go").Parse(`// Copyright (c) {{.Date.Year}} The golang.design Initiative Authors.
+// All rights reserved.
+//
+// The code below is produced by {{range $i, $a := .Authors}}{{if $i}}, {{end}}{{$a.Name}} <{{$a.Email}}>{{end}}.
+
+package {{.Package}}
+
+import "testing"
+
+func BenchmarkExample(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		// TODO: the code to measure.
+	}
+}
+`))
+
+var makefileTmpl = template.Must(template.New("Makefile").Parse(`# Copyright (c) {{.Date.Year}} The golang.design Initiative Authors.
+# All rights reserved.
+#
+# The code below is produced by {{range $i, $a := .Authors}}{{if $i}}, {{end}}{{$a.Name}} <{{$a.Email}}>{{end}}.
+
+GOVERSION=$(shell go version | awk '{print $$3}')
+all:
+	perflock -governor 80% go test -v -run=none -bench=. -count=10 | tee bench-$(GOVERSION).txt
+	benchstat bench-$(GOVERSION).txt
+`))
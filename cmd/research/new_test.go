@@ -0,0 +1,110 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.design/x/research/internal/post"
+)
+
+func TestNew(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"config.toml":       "baseURL = \"https://golang.design/research\"\n[permalinks]\n  posts = \"/:slug\"\n",
+		"data/authors.yaml": "jane:\n  name: Jane Doe\n  email: jane@example.com\njohn:\n  name: John Doe\n  email: john@example.com\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := runNew([]string{"example-post"}); err == nil {
+		t.Fatalf("runNew: expect an error for ambiguous authors")
+	}
+	if err := runNew([]string{"-author", "jane,john", "-tags", "Go,Benchmark", "example-post"}); err != nil {
+		t.Fatalf("runNew: %v", err)
+	}
+	if err := runNew([]string{"-author", "jane", "example-post"}); err == nil {
+		t.Fatalf("runNew: expect an error for an existing post")
+	}
+
+	b, err := os.ReadFile(filepath.Join(root, "content", "posts", "example-post.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := post.Meta(b)
+	if err != nil {
+		t.Fatalf("Meta: %v", err)
+	}
+	if meta["title"] != "Example Post" || meta["slug"] != "/example-post" {
+		t.Fatalf("Meta: got %v", meta)
+	}
+	authors, err := post.Authors(b)
+	if err != nil {
+		t.Fatalf("Authors: %v", err)
+	}
+	want := []post.Author{{Name: "Jane Doe", Email: "jane@example.com"}, {Name: "John Doe", Email: "john@example.com"}}
+	if !reflect.DeepEqual(authors, want) {
+		t.Fatalf("Authors: got %v, want %v", authors, want)
+	}
+	if got := post.Permalink(b); got != "https://golang.design/research/example-post" {
+		t.Fatalf("Permalink: got %v", got)
+	}
+	if _, err := post.Abstract(b); err != nil {
+		t.Fatalf("Abstract: %v", err)
+	}
+	if _, err := post.Body(b); err != nil {
+		t.Fatalf("Body: %v", err)
+	}
+	if _, err := post.References(b); err != nil {
+		t.Fatalf("References: %v", err)
+	}
+
+	for _, name := range []string{"go.mod", "bench_test.go", "Makefile"} {
+		if _, err := os.Stat(filepath.Join(root, "content", "assets", "example-post", name)); err != nil {
+			t.Fatalf("missing asset: %v", err)
+		}
+	}
+
+	// The title and tags are quoted in the front matter if needed.
+	if err := runNew([]string{"-author", "jane", "-title", "Go: #1 generics", "-tags", "a: b,#c", "2022-review"}); err != nil {
+		t.Fatalf("runNew: %v", err)
+	}
+	b, err = os.ReadFile(filepath.Join(root, "content", "posts", "2022-review.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err = post.Meta(b)
+	if err != nil {
+		t.Fatalf("Meta: %v", err)
+	}
+	if meta["title"] != "Go: #1 generics" || !reflect.DeepEqual(meta["tags"], []any{"a: b", "#c"}) {
+		t.Fatalf("Meta: got %v", meta)
+	}
+	b, err = os.ReadFile(filepath.Join(root, "content", "assets", "2022-review", "bench_test.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte("package bench2022review_test\n")) {
+		t.Fatalf("bench_test.go: got\n%s", b)
+	}
+}
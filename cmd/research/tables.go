@@ -0,0 +1,112 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.design/x/research/internal/benchstat"
+	"golang.design/x/research/internal/site"
+	"gopkg.in/yaml.v3"
+)
+
+const tablesUsage = `usage: research tables [-check]
+
+Tables summarizes the benchmark files that the posts in content/posts embed
+with the benchtable shortcode, and writes the tables to ` + benchstat.DataPath + `,
+from which Hugo renders them. Run it after adding a shortcode or updating a
+benchmark file.
+
+The flags are:
+`
+
+// tablesHeader is the header of the generated data file.
+const tablesHeader = "# Code generated by research tables. DO NOT EDIT.\n\n"
+
+func runTables(args []string) error {
+	fs := flag.NewFlagSet("tables", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), tablesUsage)
+		fs.PrintDefaults()
+	}
+	check := fs.Bool("check", false, "only check that "+benchstat.DataPath+" is up to date")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+
+	c, err := site.Load(".")
+	if err != nil {
+		return err
+	}
+	b, err := benchTables(c.Root)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(c.Root, benchstat.DataPath)
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if bytes.Equal(old, b) {
+		return nil
+	}
+	if *check {
+		return fmt.Errorf("%v is out of date, run research tables", benchstat.DataPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// benchTables returns the content of the data file with the tables of
+// all benchtable shortcodes in the posts of the site at root.
+func benchTables(root string) ([]byte, error) {
+	posts, err := filepath.Glob(filepath.Join(root, "content", "posts", "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	tables := map[string][]*benchstat.Table{}
+	for _, p := range posts {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, sc := range benchstat.Shortcodes(b) {
+			if _, ok := tables[sc.Key]; ok {
+				continue
+			}
+			files, err := benchstat.Load(root, sc.Inputs)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", filepath.Base(p), err)
+			}
+			tables[sc.Key] = benchstat.Tables(files)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(tablesHeader)
+	if len(tables) == 0 {
+		return buf.Bytes(), nil
+	}
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(tables); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -7,7 +7,7 @@
 package main
 
 // MakeChan returns a sender and a receiver of a buffered channel
-// with infinite capacity.
+// with infinite capacity. See package chann for a generic version.
 func MakeChan() (chan<- interface{}, <-chan interface{}) {
 	in, out := make(chan interface{}), make(chan interface{})
 
@@ -0,0 +1,101 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"runtime"
+	"testing"
+
+	"ultimate-chan/chann"
+)
+
+// The benchmarks compare MakeChan, whose buffer is a slice that is
+// advanced by q = q[1:], with the ring buffer of package chann.
+//
+//	go test -run=none -bench=. -benchmem -count=10 | tee bench.txt
+
+// unbounded is an unbounded channel under benchmark.
+type unbounded struct {
+	in    chan<- interface{}
+	out   <-chan interface{}
+	close func()
+}
+
+var impls = []struct {
+	name string
+	make func() unbounded
+}{
+	{"MakeChan", func() unbounded {
+		in, out := MakeChan()
+		return unbounded{in, out, func() { close(in) }}
+	}},
+	{"chann", func() unbounded {
+		ch := chann.New[interface{}]()
+		return unbounded{ch.In(), ch.Out(), ch.Close}
+	}},
+}
+
+// BenchmarkThroughput measures the cost of sending a value through the
+// channel while it is received concurrently.
+func BenchmarkThroughput(b *testing.B) {
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			ch := impl.make()
+			b.ReportAllocs()
+			b.ResetTimer()
+			go func() {
+				for i := 0; i < b.N; i++ {
+					ch.in <- i
+				}
+				ch.close()
+			}()
+			for range ch.out {
+			}
+		})
+	}
+}
+
+// burst is the number of values of a burst.
+const burst = 1 << 14
+
+// BenchmarkBurst measures the cost of sending a burst of values before
+// receiving them, and reports the heap that is still in use after the
+// last burst has been received as retained-B. The values are pointers
+// to 1 KiB arrays, which stay alive as long as the buffer references
+// them.
+func BenchmarkBurst(b *testing.B) {
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			ch := impl.make()
+			defer ch.close()
+			before := heapInUse()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < burst; j++ {
+					ch.in <- new([1 << 10]byte)
+				}
+				for j := 0; j < burst; j++ {
+					<-ch.out
+				}
+			}
+			b.StopTimer()
+			retained := int64(heapInUse()) - int64(before)
+			if retained < 0 {
+				retained = 0
+			}
+			b.ReportMetric(float64(retained), "retained-B")
+		})
+	}
+}
+
+func heapInUse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapInuse
+}
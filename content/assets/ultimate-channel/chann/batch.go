@@ -0,0 +1,143 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann
+
+import (
+	"sync"
+	"time"
+)
+
+// A batch is a request of RecvBatch to the goroutine of a channel,
+// which appends the buffered values to dst up to the length want.
+type batch[T any] struct {
+	dst  []T
+	want int
+	done chan struct{}
+}
+
+// RecvBatch receives up to n values from the channel and appends them
+// to dst. It returns as soon as n values are received, or after wait
+// with the values that are received until then, which are none if no
+// value arrives in time. If wait is not positive, RecvBatch only
+// receives the values that are ready. The result is false if the
+// channel is closed and all of its values are received.
+//
+// A channel with a goroutine delivers a batch from its buffer at once,
+// rather than a value per channel operation as Out. RecvBatch does not
+// allocate if dst has the capacity for n more values.
+func (ch *Chan[T]) RecvBatch(dst []T, n int, wait time.Duration) ([]T, bool) {
+	want := len(dst) + n
+	if !ch.forwarded() {
+		return recvBatch(ch.out, dst, want, wait)
+	}
+
+	b, _ := ch.reqs.Get().(*batch[T])
+	if b == nil {
+		b = &batch[T]{done: make(chan struct{}, 1)}
+	}
+	defer func() {
+		b.dst = nil
+		ch.reqs.Put(b)
+	}()
+	var timeout <-chan time.Time
+	for len(dst) < want {
+		b.dst, b.want = dst, want
+		select {
+		case ch.batches <- b:
+			<-b.done
+			dst = b.dst
+			continue
+		case <-ch.exited:
+			return dst, false
+		default:
+		}
+		if wait <= 0 {
+			return dst, true
+		}
+		if timeout == nil {
+			t := getTimer(wait)
+			defer putTimer(t)
+			timeout = t.C
+		}
+		select {
+		case ch.batches <- b:
+			<-b.done
+			dst = b.dst
+		case <-ch.exited:
+			return dst, false
+		case <-timeout:
+			return dst, true
+		}
+	}
+	return dst, true
+}
+
+// serve appends the buffered values to the batch b.
+func (ch *Chan[T]) serve(b *batch[T]) {
+	for len(b.dst) < b.want && ch.q.len() > 0 {
+		b.dst = append(b.dst, ch.q.peek())
+		ch.dequeue()
+	}
+	b.done <- struct{}{}
+}
+
+// recvBatch receives values from c and appends them to dst up to the
+// length want, see RecvBatch.
+func recvBatch[T any](c <-chan T, dst []T, want int, wait time.Duration) ([]T, bool) {
+	var timeout <-chan time.Time
+	for len(dst) < want {
+		select {
+		case v, ok := <-c:
+			if !ok {
+				return dst, false
+			}
+			dst = append(dst, v)
+			continue
+		default:
+		}
+		if wait <= 0 {
+			return dst, true
+		}
+		if timeout == nil {
+			t := getTimer(wait)
+			defer putTimer(t)
+			timeout = t.C
+		}
+		select {
+		case v, ok := <-c:
+			if !ok {
+				return dst, false
+			}
+			dst = append(dst, v)
+		case <-timeout:
+			return dst, true
+		}
+	}
+	return dst, true
+}
+
+// timers are the stopped timers of RecvBatch, which are reused to
+// receive a batch without allocation.
+var timers sync.Pool
+
+func getTimer(d time.Duration) *time.Timer {
+	if t, ok := timers.Get().(*time.Timer); ok {
+		t.Reset(d)
+		return t
+	}
+	return time.NewTimer(d)
+}
+
+func putTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	timers.Put(t)
+}
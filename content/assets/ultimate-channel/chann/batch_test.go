@@ -0,0 +1,109 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"ultimate-chan/chann"
+)
+
+func TestRecvBatch(t *testing.T) {
+	for name, opts := range map[string][]chann.Opt{
+		"buffered":  {chann.Cap(10)},
+		"unbounded": nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ch := chann.New[int](opts...)
+			for i := 0; i < 5; i++ {
+				ch.In() <- i
+			}
+			for ch.Len() < 5 { // until the goroutine buffers all
+				time.Sleep(time.Millisecond)
+			}
+
+			dst := make([]int, 0, 8)
+			got, ok := ch.RecvBatch(dst, 3, time.Second)
+			if !ok || !reflect.DeepEqual(got, []int{0, 1, 2}) {
+				t.Fatalf("RecvBatch: got %v, %v, want [0 1 2], true", got, ok)
+			}
+			// Only 2 values arrive in time.
+			got, ok = ch.RecvBatch(got[:0], 3, 10*time.Millisecond)
+			if !ok || !reflect.DeepEqual(got, []int{3, 4}) {
+				t.Fatalf("RecvBatch: got %v, %v, want [3 4], true", got, ok)
+			}
+			got, ok = ch.RecvBatch(got[:0], 3, 0)
+			if !ok || len(got) != 0 {
+				t.Fatalf("RecvBatch: got %v, %v, want [], true", got, ok)
+			}
+
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				ch.In() <- 5
+				ch.Close()
+			}()
+			got, ok = ch.RecvBatch(got[:0], 3, time.Minute)
+			if ok || !reflect.DeepEqual(got, []int{5}) {
+				t.Fatalf("RecvBatch: got %v, %v, want [5], false", got, ok)
+			}
+		})
+	}
+}
+
+func TestRecvBatchAllocs(t *testing.T) {
+	ch := chann.New[int]()
+	defer ch.CloseAndDiscard()
+	dst := make([]int, 0, 8)
+	allocs := testing.AllocsPerRun(100, func() {
+		for i := 0; i < 8; i++ {
+			ch.In() <- i
+		}
+		n := 0
+		for n < 8 {
+			var b []int
+			b, _ = ch.RecvBatch(dst, 8-n, time.Second)
+			n += len(b)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("RecvBatch: got %v allocations, want 0", allocs)
+	}
+}
+
+func BenchmarkRecv(b *testing.B) {
+	const n = 64
+	b.Run("Out", func(b *testing.B) {
+		ch := chann.New[int]()
+		defer ch.CloseAndDiscard()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < n; j++ {
+				ch.In() <- j
+			}
+			for j := 0; j < n; j++ {
+				<-ch.Out()
+			}
+		}
+	})
+	b.Run("RecvBatch", func(b *testing.B) {
+		ch := chann.New[int]()
+		defer ch.CloseAndDiscard()
+		dst := make([]int, 0, n)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < n; j++ {
+				ch.In() <- j
+			}
+			for m := 0; m < n; {
+				batch, _ := ch.RecvBatch(dst, n-m, time.Second)
+				m += len(batch)
+			}
+		}
+	})
+}
@@ -0,0 +1,147 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann
+
+import "sync"
+
+// Broadcast is a channel that delivers every value to all of its
+// subscribers, e.g. a drawing call to the window, the recorder and the
+// statistics of a renderer.
+//
+// Each subscriber is a Chan with its own buffer, which is unbounded or
+// has an overflow policy that never blocks, so that a slow subscriber
+// neither blocks the publisher nor the other subscribers. Subscribers
+// join and leave at any time, and receive the values that are sent
+// while they are subscribed.
+type Broadcast[T any] struct {
+	in   chan T
+	done chan struct{} // closed when the goroutine exits
+	leak *record       // the creation of the channel in debug mode
+
+	mu       sync.Mutex
+	subs     map[*Chan[T]]bool
+	isClosed bool
+}
+
+// NewBroadcast returns a new broadcast channel. Its goroutine forwards
+// the values from In to the subscribers until the channel is closed.
+func NewBroadcast[T any]() *Broadcast[T] {
+	b := &Broadcast[T]{
+		in:   make(chan T),
+		done: make(chan struct{}),
+		subs: map[*Chan[T]]bool{},
+	}
+	b.leak = track()
+	go b.forward()
+	return b
+}
+
+// In returns the channel to publish values to. It must not be closed
+// by the built-in close; use Close instead.
+func (b *Broadcast[T]) In() chan<- T { return b.in }
+
+// Subscribe returns a new subscriber of the channel, which is created
+// by New with the given options. By default, a subscriber is unbounded.
+// Subscribe panics if the options make a subscriber that may block the
+// publisher, i.e. a buffered channel with the Block policy. A subscriber
+// of a closed channel is closed.
+//
+// A subscriber leaves by Unsubscribe, or by closing itself.
+func (b *Broadcast[T]) Subscribe(opts ...Opt) *Chan[T] {
+	cfg := config{cap: -1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.cap >= 0 && cfg.policy == Block {
+		panic("chann: a subscriber with the Block policy may block the publisher")
+	}
+	sub := New[T](opts...)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.isClosed {
+		sub.Close()
+		return sub
+	}
+	b.subs[sub] = true
+	return sub
+}
+
+// Unsubscribe removes the subscriber, and abandons it by
+// CloseAndDiscard.
+func (b *Broadcast[T]) Unsubscribe(sub *Chan[T]) {
+	b.mu.Lock()
+	delete(b.subs, sub)
+	b.mu.Unlock()
+	sub.CloseAndDiscard()
+}
+
+// Subscribers returns the number of subscribers.
+func (b *Broadcast[T]) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Close closes the channel and all of its subscribers, which still
+// deliver their buffered values. It returns after the last published
+// value is forwarded to the subscribers. Like the built-in close,
+// publishing to a closed channel or closing it again panics.
+func (b *Broadcast[T]) Close() {
+	b.mu.Lock()
+	if b.isClosed {
+		b.mu.Unlock()
+		panic("chann: close of closed broadcast")
+	}
+	b.isClosed = true
+	close(b.in)
+	b.mu.Unlock()
+	<-b.done
+}
+
+// forward forwards the values from in to the subscribers.
+func (b *Broadcast[T]) forward() {
+	defer close(b.done)
+	defer untrack(b.leak)
+	for v := range b.in {
+		b.mu.Lock()
+		for sub := range b.subs {
+			if !b.send(sub, v) {
+				delete(b.subs, sub)
+			}
+		}
+		b.mu.Unlock()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		sub.mu.Lock()
+		if !sub.isClosed {
+			sub.close()
+		}
+		sub.mu.Unlock()
+		delete(b.subs, sub)
+	}
+}
+
+// send sends v to the subscriber, and reports whether it is still
+// subscribed. The goroutine of the subscriber receives v immediately
+// unless it has exited, e.g. as its context is done.
+func (b *Broadcast[T]) send(sub *Chan[T], v T) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.isClosed {
+		return false
+	}
+	select {
+	case sub.in <- v:
+		return true
+	case <-sub.exited:
+		return false
+	}
+}
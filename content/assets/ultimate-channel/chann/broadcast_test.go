@@ -0,0 +1,79 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann_test
+
+import (
+	"reflect"
+	"testing"
+
+	"ultimate-chan/chann"
+)
+
+func TestBroadcast(t *testing.T) {
+	b := chann.NewBroadcast[int]()
+	all := b.Subscribe()
+	latest := b.Subscribe(chann.Cap(1), chann.Overflow(chann.DropOldest)) // never received until the end
+	left := b.Subscribe()
+	closed := b.Subscribe()
+	closed.Close()
+
+	received := make(chan []int)
+	go func() {
+		vs := []int{}
+		for v := range all.Out() {
+			vs = append(vs, v)
+		}
+		received <- vs
+	}()
+
+	for i := 0; i < 100; i++ {
+		b.In() <- i
+		if i == 49 {
+			b.Unsubscribe(left)
+		}
+	}
+	if n := b.Subscribers(); n != 2 {
+		t.Fatalf("Subscribers: got %d, want 2", n)
+	}
+	b.Close()
+
+	want := []int{}
+	for i := 0; i < 100; i++ {
+		want = append(want, i)
+	}
+	if got := <-received; !reflect.DeepEqual(got, want) {
+		t.Fatalf("subscriber: got %v, want %v", got, want)
+	}
+	if got := drain(latest); !reflect.DeepEqual(got, []int{99}) {
+		t.Fatalf("slow subscriber: got %v, want [99]", got)
+	}
+	if got := drain(left); len(got) != 0 {
+		t.Fatalf("unsubscribed subscriber: got %v", got)
+	}
+	if got := drain(b.Subscribe()); len(got) != 0 {
+		t.Fatalf("subscriber after Close: got %v", got)
+	}
+}
+
+func TestBroadcastBlock(t *testing.T) {
+	b := chann.NewBroadcast[int]()
+	defer b.Close()
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Subscribe: no panic")
+		}
+	}()
+	b.Subscribe(chann.Cap(1))
+}
+
+func drain(ch *chann.Chan[int]) []int {
+	vs := []int{}
+	for v := range ch.Out() {
+		vs = append(vs, v)
+	}
+	return vs
+}
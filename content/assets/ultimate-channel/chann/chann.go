@@ -0,0 +1,417 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+// Package chann provides a unified representation of unbuffered,
+// buffered and unbounded channels in Go.
+//
+// The built-in make creates an unbuffered or a buffered channel, whose
+// capacity is finite:
+//
+//	ch := make(chan int)     // unbuffered channel
+//	ch := make(chan int, 42) // buffered channel
+//
+// New creates all three kinds of channels, where the capacity is given
+// by Cap:
+//
+//	ch := chann.New[int](chann.Cap(0))  // unbuffered channel
+//	ch := chann.New[int](chann.Cap(42)) // buffered channel
+//	ch := chann.New[int]()              // unbounded channel
+//	ch := chann.New[int](chann.Cap(-1)) // unbounded channel
+//
+// A Chan is a single value that is sent to by In and received from by
+// Out, so that the sender and the receiver cannot be mixed up as with
+// the two channels returned by MakeChan. A Chan is closed by its Close
+// method, and the values that are sent before remain available from
+// Out, which is closed after the last value is received. The goroutine
+// of an unbounded channel only exits then, thus a channel whose values
+// are no longer received is abandoned by CloseAndDiscard, or by the
+// cancellation of its Context. SetDebug and CheckLeaks find the channels
+// that are never abandoned in tests.
+//
+// The Stats of a channel count its values, and SoftLimit reports a
+// channel that grows unexpectedly before it runs out of memory. A
+// channel with a Name publishes its stats with the expvar package.
+//
+// A sender of a full buffered channel blocks by default. Overflow
+// selects another policy, which never blocks the sender but discards
+// values instead:
+//
+//	ch := chann.New[int](chann.Cap(42), chann.Overflow(chann.DropOldest))
+//
+// RecvBatch receives up to a number of values at once, or the values
+// that arrive within a maximum wait, e.g. to write them in one call.
+//
+// A Broadcast delivers every value to all of its subscribers, each of
+// which is a Chan, and a Priority channel delivers the values of higher
+// priorities first. A Spill channel keeps a budget of values in memory
+// and spills the others to disk, instead of running out of memory.
+//
+// See https://golang.design/research/ultimate-channel for the
+// motivation of this package.
+package chann
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Opt is an option to configure a channel created by New.
+type Opt func(*config)
+
+type config struct {
+	cap    int // negative for unbounded channels
+	policy Policy
+	key    any // a func(T) any of Key
+	ctx    context.Context
+
+	name        string // the name of the expvar of Name
+	softLimit   int
+	onSoftLimit func(Stats)
+}
+
+// Cap configures the capacity of a channel. If n is 0, the channel is
+// unbuffered; if n is positive, the channel is buffered with capacity
+// n; and if n is negative, the channel is unbounded.
+func Cap(n int) Opt {
+	return func(c *config) {
+		if n < 0 {
+			n = -1
+		}
+		c.cap = n
+	}
+}
+
+// Context configures the context of a channel. When the context is
+// done, the goroutine of the channel discards the buffered values,
+// closes Out and exits, while In is no longer received from. A channel
+// with a context always has a goroutine.
+func Context(ctx context.Context) Opt {
+	return func(c *config) { c.ctx = ctx }
+}
+
+// Chan is a generic channel that is either unbuffered, buffered or
+// unbounded.
+type Chan[T any] struct {
+	// The atomics are first for their 64-bit alignment.
+	len       int64 // the length of q
+	highWater int64
+	enqueued  int64
+	dequeued  int64
+	dropped   int64
+	wait      int64 // in nanoseconds
+
+	in      chan T
+	out     chan T
+	closing chan struct{} // closed by Close before in
+	discard chan struct{} // closed by CloseAndDiscard before closing
+	exited  chan struct{} // closed when the goroutine exits
+	cfg     config
+	leak    *record // the creation of the channel in debug mode
+	pub     *publication
+	batches chan *batch[T]
+	reqs    sync.Pool // of *batch[T]
+
+	mu          sync.Mutex
+	isClosed    bool
+	isDiscarded bool
+
+	// The internal buffer of a channel with a goroutine, which is only
+	// accessed by the goroutine, and the times when its values are
+	// buffered since the creation of the channel. The keys of Coalesce
+	// map to the positions of their values in q, counted from the first
+	// value that was ever buffered.
+	q         queue[T]
+	times     queue[time.Duration]
+	created   time.Time
+	key       func(T) any
+	keys      map[any]int
+	popped    int
+	nextLimit int // the length at which the soft limit is reported next
+}
+
+// New returns a new channel. By default, the channel is unbounded, and
+// Cap configures its capacity. The values that overflow a buffered
+// channel are handled according to its Overflow policy.
+//
+// An unbounded channel, or a channel with a policy other than Block,
+// a Context, a Name or a SoftLimit, forwards the values from In to Out by a goroutine,
+// which exits after the channel is closed and all values are received
+// from Out, after CloseAndDiscard, or when its context is done.
+//
+// New panics if the options are inconsistent.
+func New[T any](opts ...Opt) *Chan[T] {
+	ch := &Chan[T]{
+		cfg:     config{cap: -1},
+		closing: make(chan struct{}),
+		discard: make(chan struct{}),
+		exited:  make(chan struct{}),
+		batches: make(chan *batch[T]),
+	}
+	for _, opt := range opts {
+		opt(&ch.cfg)
+	}
+	switch {
+	case ch.cfg.policy != Block && ch.cfg.cap == 0:
+		panic("chann: an unbuffered channel cannot have an overflow policy")
+	case (ch.cfg.name != "" || ch.cfg.softLimit > 0) && ch.cfg.cap == 0:
+		panic("chann: an unbuffered channel has no buffer to observe")
+	case ch.cfg.key != nil && ch.cfg.policy != Coalesce:
+		panic("chann: Key requires the Coalesce policy")
+	case ch.cfg.policy == Coalesce:
+		ch.key = func(T) any { return nil }
+		if ch.cfg.key != nil {
+			key, ok := ch.cfg.key.(func(T) any)
+			if !ok {
+				panic("chann: Key of a different type than the values")
+			}
+			ch.key = key
+		}
+		ch.keys = map[any]int{}
+	}
+	if !ch.forwarded() {
+		ch.in = make(chan T, ch.cfg.cap)
+		ch.out = ch.in
+		return ch
+	}
+	ch.in, ch.out = make(chan T), make(chan T)
+	ch.created = time.Now()
+	ch.nextLimit = ch.cfg.softLimit
+	ch.leak = track()
+	if ch.cfg.name != "" {
+		ch.pub = publish(ch.cfg.name, expvar.Func(func() any { return ch.Stats() }))
+	}
+	go ch.forward()
+	return ch
+}
+
+// forwarded reports whether the values of the channel are forwarded by
+// a goroutine.
+func (ch *Chan[T]) forwarded() bool {
+	return ch.cfg.cap < 0 || ch.cfg.policy != Block || ch.cfg.ctx != nil ||
+		ch.cfg.name != "" || ch.cfg.softLimit > 0
+}
+
+// In returns the channel to send values to. It must not be closed by
+// the built-in close, which panics for an unbounded channel; use Close
+// instead.
+func (ch *Chan[T]) In() chan<- T { return ch.in }
+
+// Out returns the channel to receive values from.
+func (ch *Chan[T]) Out() <-chan T { return ch.out }
+
+// Close closes the channel. The values that are sent before can still
+// be received from Out, which is closed after the last of them. Like
+// the built-in close, sending to a closed channel or closing it again
+// panics.
+//
+// If the values are never received, the goroutine of the channel does
+// not exit; use CloseAndDiscard to abandon a channel.
+func (ch *Chan[T]) Close() {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.isClosed {
+		panic("chann: close of closed channel")
+	}
+	ch.close()
+}
+
+func (ch *Chan[T]) close() {
+	ch.isClosed = true
+	close(ch.closing)
+	close(ch.in)
+}
+
+// CloseAndDiscard closes the channel and discards the buffered values,
+// so that Out is closed without them. Unlike Close, it may be called
+// after Close and more than once, e.g. to abandon a closed channel
+// whose values are no longer received. It returns after the goroutine
+// of the channel has exited.
+func (ch *Chan[T]) CloseAndDiscard() {
+	ch.mu.Lock()
+	if !ch.isDiscarded {
+		ch.isDiscarded = true
+		close(ch.discard)
+	}
+	if !ch.isClosed {
+		ch.close()
+	}
+	ch.mu.Unlock()
+
+	if ch.forwarded() {
+		<-ch.exited
+		return
+	}
+	for range ch.in {
+	}
+}
+
+// Len returns the number of values that are buffered in the channel.
+// Like the built-in len, the result may be out of date as soon as it is
+// returned if the channel is used concurrently.
+func (ch *Chan[T]) Len() int {
+	if !ch.forwarded() {
+		return len(ch.in)
+	}
+	return int(atomic.LoadInt64(&ch.len))
+}
+
+// Cap returns the capacity of the channel, which is -1 for an unbounded
+// channel.
+func (ch *Chan[T]) Cap() int {
+	return ch.cfg.cap
+}
+
+// forward forwards the values of the channel from in to out through
+// the internal buffer.
+func (ch *Chan[T]) forward() {
+	var done <-chan struct{}
+	if ch.cfg.ctx != nil {
+		done = ch.cfg.ctx.Done()
+	}
+	for {
+		// A done context takes precedence over the pending values.
+		select {
+		case <-done:
+			ch.exit()
+			return
+		default:
+		}
+
+		var (
+			in      = ch.in
+			out     chan T // nil while there is nothing to send
+			next    T
+			batches chan *batch[T]
+		)
+		if ch.q.len() > 0 {
+			out, next, batches = ch.out, ch.q.peek(), ch.batches
+		}
+		if ch.cfg.policy == Block && ch.cfg.cap >= 0 && ch.q.len() >= ch.cfg.cap {
+			in = nil // the senders block while the buffer is full
+		}
+		select {
+		case out <- next:
+			ch.dequeue()
+		case b := <-batches:
+			ch.serve(b)
+		case e, ok := <-in:
+			if !ok {
+				ch.flush(done)
+				return
+			}
+			ch.push(e)
+		case <-ch.discard: // in may be nil while the buffer is full
+			ch.exit()
+			return
+		case <-done:
+			ch.exit()
+			return
+		}
+	}
+}
+
+// push buffers the value e according to the overflow policy.
+func (ch *Chan[T]) push(e T) {
+	atomic.AddInt64(&ch.enqueued, 1)
+	var k any
+	if ch.key != nil {
+		k = ch.key(e)
+		if i, ok := ch.keys[k]; ok {
+			*ch.q.at(i - ch.popped) = e
+			atomic.AddInt64(&ch.dropped, 1)
+			return
+		}
+	}
+	if ch.cfg.cap >= 0 && ch.q.len() >= ch.cfg.cap {
+		atomic.AddInt64(&ch.dropped, 1)
+		if ch.cfg.policy == DropNewest {
+			return
+		}
+		ch.pop() // DropOldest and Coalesce
+	}
+	if ch.key != nil {
+		ch.keys[k] = ch.popped + ch.q.len()
+	}
+	ch.q.push(e)
+	ch.times.push(time.Since(ch.created))
+	n := atomic.AddInt64(&ch.len, 1)
+	if n > atomic.LoadInt64(&ch.highWater) {
+		atomic.StoreInt64(&ch.highWater, n)
+	}
+	if ch.nextLimit > 0 && int(n) >= ch.nextLimit {
+		ch.nextLimit *= 2
+		ch.reportSoftLimit()
+	}
+}
+
+// dequeue removes the first buffered value after it is received.
+func (ch *Chan[T]) dequeue() {
+	t := ch.pop()
+	atomic.AddInt64(&ch.dequeued, 1)
+	atomic.AddInt64(&ch.wait, int64(time.Since(ch.created)-t))
+}
+
+// pop removes the first buffered value, and returns the time when it
+// was buffered.
+func (ch *Chan[T]) pop() time.Duration {
+	e := ch.q.pop()
+	if ch.key != nil {
+		if k := ch.key(e); ch.keys[k] == ch.popped {
+			delete(ch.keys, k)
+		}
+	}
+	ch.popped++
+	if n := atomic.AddInt64(&ch.len, -1); int(n) < ch.cfg.softLimit {
+		ch.nextLimit = ch.cfg.softLimit
+	}
+	return ch.times.pop()
+}
+
+// flush delivers the buffered values after in is closed, unless they
+// are discarded or the context is done, and exits.
+func (ch *Chan[T]) flush(done <-chan struct{}) {
+	select {
+	case <-ch.closing:
+	default:
+		panic("chann: In closed by the built-in close, use Close instead")
+	}
+	for ch.q.len() > 0 {
+		select {
+		case <-ch.discard:
+			ch.exit()
+			return
+		default:
+		}
+		select {
+		case ch.out <- ch.q.peek():
+			ch.dequeue()
+		case b := <-ch.batches:
+			ch.serve(b)
+		case <-ch.discard:
+		case <-done:
+			ch.exit()
+			return
+		}
+	}
+	ch.exit()
+}
+
+// exit discards the buffered values and closes out.
+func (ch *Chan[T]) exit() {
+	ch.q = queue[T]{}
+	ch.times = queue[time.Duration]{}
+	ch.keys = nil
+	atomic.StoreInt64(&ch.len, 0)
+	if ch.pub != nil {
+		ch.pub.unpublish()
+	}
+	untrack(ch.leak)
+	close(ch.out)
+	close(ch.exited)
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"ultimate-chan/chann"
+)
+
+func TestMain(m *testing.M) {
+	chann.SetDebug(true)
+	code := m.Run()
+	if err := chann.CheckLeaks(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		code = 1
+	}
+	os.Exit(code)
+}
+
+func TestChan(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []chann.Opt
+		cap  int
+	}{
+		{"unbuffered", []chann.Opt{chann.Cap(0)}, 0},
+		{"buffered", []chann.Opt{chann.Cap(42)}, 42},
+		{"unbounded", nil, -1},
+		{"unbounded-negative", []chann.Opt{chann.Cap(-42)}, -1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := chann.New[int](tt.opts...)
+			if got := ch.Cap(); got != tt.cap {
+				t.Fatalf("Cap: got %d, want %d", got, tt.cap)
+			}
+
+			const n = 1000
+			go func() {
+				for i := 0; i < n; i++ {
+					ch.In() <- i
+				}
+				ch.Close()
+			}()
+			i := 0
+			for v := range ch.Out() {
+				if v != i {
+					t.Fatalf("Out: got %d, want %d", v, i)
+				}
+				i++
+			}
+			if i != n {
+				t.Fatalf("Out: got %d values, want %d", i, n)
+			}
+		})
+	}
+}
+
+func TestUnboundedLen(t *testing.T) {
+	ch := chann.New[int]()
+	const n = 100
+	for i := 0; i < n; i++ {
+		ch.In() <- i
+	}
+	// The last value may still be on its way to the buffer.
+	if got := ch.Len(); got != n && got != n-1 {
+		t.Fatalf("Len: got %d, want %d", got, n)
+	}
+	ch.Close()
+	for i := 0; i < n; i++ {
+		if v := <-ch.Out(); v != i {
+			t.Fatalf("Out: got %d, want %d", v, i)
+		}
+	}
+	if _, ok := <-ch.Out(); ok {
+		t.Fatalf("Out: not closed after the last value")
+	}
+	if got := ch.Len(); got != 0 {
+		t.Fatalf("Len: got %d, want 0", got)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// leakGrace is how long CheckLeaks waits for the goroutines that are
+// about to exit.
+const leakGrace = time.Second
+
+// debug is the registry of the channels whose goroutines are running,
+// which are created in debug mode.
+var debug struct {
+	sync.Mutex
+	enabled bool
+	alive   map[*record]bool
+}
+
+// A record is the creation of a channel.
+type record struct {
+	stack string
+}
+
+// SetDebug enables or disables the debug mode. In debug mode, New
+// records the creation stack of each channel with a goroutine, until
+// the goroutine exits, and CheckLeaks reports the channels whose
+// goroutines are still running. It is meant for tests, e.g.
+//
+//	func TestMain(m *testing.M) {
+//		chann.SetDebug(true)
+//		code := m.Run()
+//		if err := chann.CheckLeaks(); err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			code = 1
+//		}
+//		os.Exit(code)
+//	}
+func SetDebug(enabled bool) {
+	debug.Lock()
+	defer debug.Unlock()
+	debug.enabled = enabled
+}
+
+// CheckLeaks returns an error that lists the creation stacks of the
+// channels created in debug mode whose goroutines are still running,
+// i.e. that are not closed, or whose values are not received after
+// they are closed. It waits a moment for the goroutines that are about
+// to exit.
+func CheckLeaks() error {
+	deadline := time.Now().Add(leakGrace)
+	for {
+		debug.Lock()
+		stacks := []string{}
+		for r := range debug.alive {
+			stacks = append(stacks, r.stack)
+		}
+		debug.Unlock()
+		if len(stacks) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("chann: %d leaked channels:\n\n%s", len(stacks), strings.Join(stacks, "\n"))
+		}
+		time.Sleep(leakGrace / 100)
+	}
+}
+
+// track records the creation of a channel by its caller in debug mode,
+// or returns nil otherwise.
+func track() *record {
+	debug.Lock()
+	defer debug.Unlock()
+	if !debug.enabled {
+		return nil
+	}
+
+	// Skip runtime.Callers, track and New.
+	pcs := make([]uintptr, 32)
+	pcs = pcs[:runtime.Callers(3, pcs)]
+	var b strings.Builder
+	b.WriteString("channel created at:\n")
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	r := &record{stack: b.String()}
+	if debug.alive == nil {
+		debug.alive = map[*record]bool{}
+	}
+	debug.alive[r] = true
+	return r
+}
+
+// untrack removes the record of a channel whose goroutine exits.
+func untrack(r *record) {
+	if r == nil {
+		return
+	}
+	debug.Lock()
+	defer debug.Unlock()
+	delete(debug.alive, r)
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann_test
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"ultimate-chan/chann"
+)
+
+func TestCloseAndDiscard(t *testing.T) {
+	for name, opts := range map[string][]chann.Opt{
+		"buffered":  {chann.Cap(42)},
+		"unbounded": nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ch := chann.New[int](opts...)
+			for i := 0; i < 10; i++ {
+				ch.In() <- i
+			}
+			ch.CloseAndDiscard()
+			if v, ok := <-ch.Out(); ok {
+				t.Fatalf("Out: got %d after CloseAndDiscard", v)
+			}
+			if n := ch.Len(); n != 0 {
+				t.Fatalf("Len: got %d, want 0", n)
+			}
+			ch.CloseAndDiscard() // no panic
+		})
+	}
+}
+
+// TestCloseAndDiscardFull abandons a full buffered channel with the
+// Block policy and a goroutine, which no longer receives from In.
+func TestCloseAndDiscardFull(t *testing.T) {
+	for name, opt := range map[string]chann.Opt{
+		"context":    chann.Context(context.Background()),
+		"name":       chann.Name("full"),
+		"soft-limit": chann.SoftLimit(1, func(chann.Stats) {}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ch := chann.New[int](chann.Cap(1), opt)
+			ch.In() <- 1
+			for ch.Len() < 1 { // until the goroutine buffers the value
+				runtime.Gosched()
+			}
+			ch.CloseAndDiscard()
+			if v, ok := <-ch.Out(); ok {
+				t.Fatalf("Out: got %d after CloseAndDiscard", v)
+			}
+		})
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	ch := chann.New[int]()
+	ch.Close()
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Close: no panic when closed twice")
+		}
+		ch.CloseAndDiscard()
+	}()
+	ch.Close()
+}
+
+func TestContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := chann.New[int](chann.Cap(42), chann.Context(ctx))
+	for i := 0; i < 10; i++ {
+		ch.In() <- i
+	}
+	if v := <-ch.Out(); v != 0 {
+		t.Fatalf("Out: got %d, want 0", v)
+	}
+	cancel()
+	// The goroutine may deliver one more value before it notices that
+	// the context is done.
+	n := 0
+	for range ch.Out() {
+		n++
+	}
+	if n > 1 {
+		t.Fatalf("Out: got %d values after cancellation", n)
+	}
+	ch.Close() // no panic
+}
+
+func TestCheckLeaks(t *testing.T) {
+	ch := chann.New[int]()
+	ch.In() <- 1
+	ch.Close() // but 1 is never received
+	err := chann.CheckLeaks()
+	if err == nil || !strings.Contains(err.Error(), "TestCheckLeaks") {
+		t.Fatalf("CheckLeaks: got %v, want the creation in TestCheckLeaks", err)
+	}
+	ch.CloseAndDiscard()
+	if err := chann.CheckLeaks(); err != nil {
+		t.Fatalf("CheckLeaks after CloseAndDiscard: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann
+
+// Policy is the policy of a buffered channel for the values that are
+// sent while its buffer is full.
+type Policy int
+
+const (
+	// Block blocks the sender until there is space in the buffer,
+	// like a built-in buffered channel. It is the default policy.
+	Block Policy = iota
+	// DropOldest discards the oldest buffered value for the new one.
+	DropOldest
+	// DropNewest discards the new value.
+	DropNewest
+	// Coalesce keeps only the latest value of each key in the buffer:
+	// a value replaces the buffered value of the same key in its place,
+	// or else it is buffered, discarding the oldest buffered value if
+	// the buffer is full. Without Key, all values have the same key,
+	// thus a receiver always receives the latest value.
+	Coalesce
+)
+
+// Overflow configures the policy of a channel for the values that
+// overflow its buffer. The policy of an unbounded channel only matters
+// for Coalesce, as it never overflows.
+//
+// For example, a renderer that must never block on its drawing calls,
+// and whose drawing calls of an outdated profile are worthless, keeps
+// only the latest drawing call of each profile:
+//
+//	draw := chann.New[drawCall](chann.Cap(16), chann.Overflow(chann.Coalesce),
+//		chann.Key(func(c drawCall) int { return c.profileID }))
+func Overflow(p Policy) Opt {
+	return func(c *config) { c.policy = p }
+}
+
+// Key configures the key of the values of a channel with the Coalesce
+// policy. The type T must be the type of the values of the channel.
+func Key[T any, K comparable](key func(T) K) Opt {
+	return func(c *config) {
+		c.key = func(v T) any { return key(v) }
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann_test
+
+import (
+	"reflect"
+	"testing"
+
+	"ultimate-chan/chann"
+)
+
+func TestOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		opts    []chann.Opt
+		want    []int
+		dropped int64
+	}{
+		{"drop-oldest", []chann.Opt{chann.Cap(3), chann.Overflow(chann.DropOldest)}, []int{7, 8, 9}, 7},
+		{"drop-newest", []chann.Opt{chann.Cap(3), chann.Overflow(chann.DropNewest)}, []int{0, 1, 2}, 7},
+		{"coalesce", []chann.Opt{chann.Cap(3), chann.Overflow(chann.Coalesce)}, []int{9}, 9},
+		{"coalesce-key", []chann.Opt{chann.Overflow(chann.Coalesce), chann.Key(func(v int) int { return v % 3 })}, []int{9, 7, 8}, 7},
+		{"coalesce-key-full", []chann.Opt{chann.Cap(3), chann.Overflow(chann.Coalesce), chann.Key(func(v int) bool { return v < 5 })}, []int{4, 9}, 8},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := chann.New[int](tt.opts...)
+			// The sends never block, and the values are buffered in
+			// order before the channel is closed.
+			for i := 0; i < 10; i++ {
+				ch.In() <- i
+			}
+			ch.Close()
+			got := []int{}
+			for v := range ch.Out() {
+				got = append(got, v)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Out: got %v, want %v", got, tt.want)
+			}
+			if d := ch.Stats().Dropped; d != tt.dropped {
+				t.Fatalf("Stats: got dropped %d, want %d", d, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestOverflowInvalid(t *testing.T) {
+	for name, opts := range map[string][]chann.Opt{
+		"unbuffered": {chann.Cap(0), chann.Overflow(chann.DropOldest)},
+		"key":        {chann.Key(func(v int) int { return v })},
+		"key-type":   {chann.Overflow(chann.Coalesce), chann.Key(func(v string) string { return v })},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("New: no panic")
+				}
+			}()
+			chann.New[int](opts...)
+		})
+	}
+}
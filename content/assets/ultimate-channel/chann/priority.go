@@ -0,0 +1,253 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// An Item is a value with a priority, which is sent to a Priority
+// channel.
+type Item[T any] struct {
+	Priority int // the higher, the more urgent
+	Value    T
+}
+
+// Priority is an unbounded channel that delivers the pending value of
+// the highest priority first, e.g. the resize events of a window before
+// its queued drawing calls. The values of the same priority are
+// delivered in the order in which they are sent.
+//
+// A steady stream of urgent values would starve the values of lower
+// priorities. With starvation protection, after a number of values have
+// overtaken the values of lower priorities in a row, the oldest pending
+// value is delivered next, regardless of its priority.
+type Priority[T any] struct {
+	len int64 // first for the 64-bit alignment of atomics
+
+	in         chan Item[T]
+	out        chan T
+	closing    chan struct{} // closed by Close before in
+	discard    chan struct{} // closed by CloseAndDiscard before closing
+	exited     chan struct{} // closed when the goroutine exits
+	starvation int
+	leak       *record
+
+	mu          sync.Mutex
+	isClosed    bool
+	isDiscarded bool
+
+	// The pending values by priority, which are only accessed by the
+	// goroutine of the channel.
+	levels    []int // the priorities of the pending values, ascending
+	queues    map[int]*queue[entry[T]]
+	seq       uint64 // the sequence number of the next value
+	overtaken int    // the values delivered in a row before older ones
+}
+
+// An entry is a pending value of a Priority channel, with the sequence
+// number in which it was sent.
+type entry[T any] struct {
+	v   T
+	seq uint64
+}
+
+// NewPriority returns a new priority channel. If starvation is
+// positive, the oldest pending value is delivered after starvation
+// values have overtaken it, otherwise the values of the highest
+// priority are always delivered first.
+//
+// Like an unbounded Chan, the values are forwarded from In to Out by a
+// goroutine, which exits after the channel is closed and all values are
+// received from Out, or after CloseAndDiscard.
+func NewPriority[T any](starvation int) *Priority[T] {
+	ch := &Priority[T]{
+		in:         make(chan Item[T]),
+		out:        make(chan T),
+		closing:    make(chan struct{}),
+		discard:    make(chan struct{}),
+		exited:     make(chan struct{}),
+		starvation: starvation,
+		queues:     map[int]*queue[entry[T]]{},
+	}
+	ch.leak = track()
+	go ch.forward()
+	return ch
+}
+
+// In returns the channel to send values with their priorities to. It
+// must not be closed by the built-in close; use Close instead.
+func (ch *Priority[T]) In() chan<- Item[T] { return ch.in }
+
+// Out returns the channel to receive values from.
+func (ch *Priority[T]) Out() <-chan T { return ch.out }
+
+// Close closes the channel. The values that are sent before can still
+// be received from Out in the order of their priorities, and Out is
+// closed after the last of them. Like the built-in close, sending to a
+// closed channel or closing it again panics.
+//
+// If the values are never received, the goroutine of the channel does
+// not exit; use CloseAndDiscard to abandon a channel.
+func (ch *Priority[T]) Close() {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.isClosed {
+		panic("chann: close of closed channel")
+	}
+	ch.close()
+}
+
+func (ch *Priority[T]) close() {
+	ch.isClosed = true
+	close(ch.closing)
+	close(ch.in)
+}
+
+// CloseAndDiscard closes the channel and discards the pending values,
+// so that Out is closed without them. Like Chan.CloseAndDiscard, it may
+// be called after Close and more than once, and returns after the
+// goroutine of the channel has exited.
+func (ch *Priority[T]) CloseAndDiscard() {
+	ch.mu.Lock()
+	if !ch.isDiscarded {
+		ch.isDiscarded = true
+		close(ch.discard)
+	}
+	if !ch.isClosed {
+		ch.close()
+	}
+	ch.mu.Unlock()
+	<-ch.exited
+}
+
+// Len returns the number of pending values.
+func (ch *Priority[T]) Len() int {
+	return int(atomic.LoadInt64(&ch.len))
+}
+
+// forward forwards the values from in to out in the order of their
+// priorities.
+func (ch *Priority[T]) forward() {
+	for {
+		var (
+			out  chan T // nil while there is nothing to send
+			next T
+			p    int
+		)
+		if len(ch.levels) > 0 {
+			p = ch.next()
+			out, next = ch.out, ch.queues[p].peek().v
+		}
+		select {
+		case out <- next:
+			ch.pop(p)
+		case it, ok := <-ch.in:
+			if !ok {
+				ch.flush()
+				return
+			}
+			ch.push(it)
+		}
+	}
+}
+
+// next returns the priority of the value to deliver next.
+func (ch *Priority[T]) next() int {
+	top := ch.levels[len(ch.levels)-1]
+	if ch.starvation <= 0 || ch.overtaken < ch.starvation {
+		return top
+	}
+	oldest := top
+	for _, p := range ch.levels {
+		if ch.queues[p].peek().seq < ch.queues[oldest].peek().seq {
+			oldest = p
+		}
+	}
+	return oldest
+}
+
+func (ch *Priority[T]) push(it Item[T]) {
+	q, ok := ch.queues[it.Priority]
+	if !ok {
+		q = &queue[entry[T]]{}
+		ch.queues[it.Priority] = q
+		i := sort.SearchInts(ch.levels, it.Priority)
+		ch.levels = append(ch.levels, 0)
+		copy(ch.levels[i+1:], ch.levels[i:])
+		ch.levels[i] = it.Priority
+	}
+	q.push(entry[T]{it.Value, ch.seq})
+	ch.seq++
+	atomic.AddInt64(&ch.len, 1)
+}
+
+// pop removes the first pending value of priority p after it is
+// delivered.
+func (ch *Priority[T]) pop(p int) {
+	q := ch.queues[p]
+	e := q.pop()
+
+	// Count the values in a row that overtake older values of lower
+	// priorities.
+	overtook := false
+	for _, l := range ch.levels {
+		if l < p && ch.queues[l].peek().seq < e.seq {
+			overtook = true
+			break
+		}
+	}
+	if overtook {
+		ch.overtaken++
+	} else {
+		ch.overtaken = 0
+	}
+
+	if q.len() == 0 {
+		delete(ch.queues, p)
+		i := sort.SearchInts(ch.levels, p)
+		ch.levels = append(ch.levels[:i], ch.levels[i+1:]...)
+	}
+	atomic.AddInt64(&ch.len, -1)
+}
+
+// flush delivers the pending values after in is closed, unless they
+// are discarded, and exits.
+func (ch *Priority[T]) flush() {
+	select {
+	case <-ch.closing:
+	default:
+		panic("chann: In closed by the built-in close, use Close instead")
+	}
+	for len(ch.levels) > 0 {
+		select {
+		case <-ch.discard:
+			ch.exit()
+			return
+		default:
+		}
+		p := ch.next()
+		select {
+		case ch.out <- ch.queues[p].peek().v:
+			ch.pop(p)
+		case <-ch.discard:
+		}
+	}
+	ch.exit()
+}
+
+// exit discards the pending values and closes out.
+func (ch *Priority[T]) exit() {
+	ch.levels = nil
+	ch.queues = nil
+	atomic.StoreInt64(&ch.len, 0)
+	untrack(ch.leak)
+	close(ch.out)
+	close(ch.exited)
+}
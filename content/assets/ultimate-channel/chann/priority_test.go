@@ -0,0 +1,98 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann_test
+
+import (
+	"reflect"
+	"testing"
+
+	"ultimate-chan/chann"
+)
+
+func TestPriority(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		starvation int
+		items      []chann.Item[string]
+		want       []string
+	}{
+		{
+			"priority", 0,
+			[]chann.Item[string]{{0, "draw1"}, {1, "resize1"}, {0, "draw2"}, {2, "close"}, {1, "resize2"}},
+			[]string{"close", "resize1", "resize2", "draw1", "draw2"},
+		},
+		{
+			"starvation", 2,
+			[]chann.Item[string]{{0, "draw"}, {1, "a"}, {1, "b"}, {1, "c"}, {1, "d"}},
+			[]string{"a", "b", "draw", "c", "d"},
+		},
+		{
+			"no-starvation", 0,
+			[]chann.Item[string]{{0, "draw"}, {1, "a"}, {1, "b"}, {1, "c"}, {1, "d"}},
+			[]string{"a", "b", "c", "d", "draw"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := chann.NewPriority[string](tt.starvation)
+			for _, it := range tt.items {
+				ch.In() <- it
+			}
+			ch.Close()
+			got := []string{}
+			for v := range ch.Out() {
+				got = append(got, v)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Out: got %v, want %v", got, tt.want)
+			}
+			if n := ch.Len(); n != 0 {
+				t.Fatalf("Len: got %d, want 0", n)
+			}
+		})
+	}
+}
+
+func TestPriorityConcurrent(t *testing.T) {
+	ch := chann.NewPriority[int](0)
+	go func() {
+		for i := 0; i < 1000; i++ {
+			ch.In() <- chann.Item[int]{Priority: i % 3, Value: i}
+		}
+		ch.Close()
+	}()
+	// The values of each priority are received in order.
+	last := map[int]int{0: -1, 1: -1, 2: -1}
+	n := 0
+	for v := range ch.Out() {
+		if v <= last[v%3] {
+			t.Fatalf("Out: got %d after %d", v, last[v%3])
+		}
+		last[v%3] = v
+		n++
+	}
+	if n != 1000 {
+		t.Fatalf("Out: got %d values, want 1000", n)
+	}
+}
+
+func TestPriorityCloseAndDiscard(t *testing.T) {
+	ch := chann.NewPriority[int](0)
+	for i := 0; i < 10; i++ {
+		ch.In() <- chann.Item[int]{Priority: i % 2, Value: i}
+	}
+	ch.Close()
+	<-ch.Out() // the receiver stops after the first value
+
+	ch.CloseAndDiscard()
+	if v, ok := <-ch.Out(); ok {
+		t.Fatalf("Out: got %d after CloseAndDiscard", v)
+	}
+	if n := ch.Len(); n != 0 {
+		t.Fatalf("Len: got %d, want 0", n)
+	}
+	ch.CloseAndDiscard() // no panic
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann
+
+// minQueueCap is the smallest capacity of a non-empty queue.
+const minQueueCap = 16
+
+// queue is a FIFO queue on a ring buffer, which is the internal buffer
+// of an unbounded channel.
+//
+// Unlike a slice that is advanced by q = q[1:], the queue releases the
+// references to the values that are popped, so that they can be
+// garbage collected, and reuses the space of its buffer. The buffer
+// doubles when it is full, and halves when it is at most a quarter
+// full, so that the memory of a burst is returned after the burst has
+// been received.
+type queue[T any] struct {
+	buf  []T // the capacity is zero or a power of two
+	head int // the index of the first value
+	n    int // the number of values
+}
+
+// len returns the number of values of the queue.
+func (q *queue[T]) len() int { return q.n }
+
+// push appends a value to the end of the queue.
+func (q *queue[T]) push(v T) {
+	if q.n == len(q.buf) {
+		c := 2 * len(q.buf)
+		if c == 0 {
+			c = minQueueCap
+		}
+		q.resize(c)
+	}
+	q.buf[(q.head+q.n)&(len(q.buf)-1)] = v
+	q.n++
+}
+
+// peek returns the first value of the queue. It panics if the queue is
+// empty.
+func (q *queue[T]) peek() T {
+	if q.n == 0 {
+		panic("chann: peek of an empty queue")
+	}
+	return q.buf[q.head]
+}
+
+// at returns a pointer to the i-th value of the queue.
+func (q *queue[T]) at(i int) *T {
+	if i < 0 || i >= q.n {
+		panic("chann: index out of range of the queue")
+	}
+	return &q.buf[(q.head+i)&(len(q.buf)-1)]
+}
+
+// pop removes and returns the first value of the queue. It panics if
+// the queue is empty.
+func (q *queue[T]) pop() T {
+	v := q.peek()
+	var zero T
+	q.buf[q.head] = zero
+	q.head = (q.head + 1) & (len(q.buf) - 1)
+	q.n--
+	if len(q.buf) > minQueueCap && q.n <= len(q.buf)/4 {
+		q.resize(len(q.buf) / 2)
+	}
+	return v
+}
+
+// resize moves the values of the queue to a new buffer of capacity c.
+func (q *queue[T]) resize(c int) {
+	buf := make([]T, c)
+	if q.head+q.n <= len(q.buf) {
+		copy(buf, q.buf[q.head:q.head+q.n])
+	} else {
+		m := copy(buf, q.buf[q.head:])
+		copy(buf[m:], q.buf[:q.n-m])
+	}
+	q.buf, q.head = buf, 0
+}
@@ -0,0 +1,47 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann
+
+import "testing"
+
+func TestQueue(t *testing.T) {
+	var q queue[*int]
+	next, want := 0, 0
+	// Interleave pushes and pops so that the values wrap around the
+	// end of the buffer while it grows.
+	for round := 0; round < 10; round++ {
+		for i := 0; i < 100; i++ {
+			v := next
+			q.push(&v)
+			next++
+		}
+		for i := 0; i < 50; i++ {
+			if v := *q.pop(); v != want {
+				t.Fatalf("pop: got %d, want %d", v, want)
+			}
+			want++
+		}
+	}
+	if q.len() != next-want {
+		t.Fatalf("len: got %d, want %d", q.len(), next-want)
+	}
+	peak := len(q.buf)
+	for q.len() > 0 {
+		if v := *q.pop(); v != want {
+			t.Fatalf("pop: got %d, want %d", v, want)
+		}
+		want++
+	}
+	if len(q.buf) != minQueueCap {
+		t.Fatalf("pop: buffer of %d values after draining, want %d (peak %d)", len(q.buf), minQueueCap, peak)
+	}
+	for i, v := range q.buf {
+		if v != nil {
+			t.Fatalf("pop: buffer still references a popped value at %d", i)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// A Codec encodes the values of a Spill channel to its segment files
+// and decodes them back. Each segment file is written by one encoder
+// and read by one decoder, so that a stateful encoding such as gob
+// only describes its types once per segment.
+type Codec[T any] struct {
+	NewEncoder func(w io.Writer) func(v T) error
+	NewDecoder func(r io.Reader) func() (T, error)
+}
+
+// Gob returns a Codec that encodes the values by encoding/gob.
+func Gob[T any]() Codec[T] {
+	return Codec[T]{
+		NewEncoder: func(w io.Writer) func(T) error {
+			enc := gob.NewEncoder(w)
+			return func(v T) error { return enc.Encode(&v) }
+		},
+		NewDecoder: func(r io.Reader) func() (T, error) {
+			dec := gob.NewDecoder(r)
+			return func() (v T, err error) {
+				err = dec.Decode(&v)
+				return v, err
+			}
+		},
+	}
+}
+
+// Binary returns a Codec that encodes the values by their
+// MarshalBinary and UnmarshalBinary methods, e.g. of time.Time, where
+// each value is prefixed by the length of its encoding.
+func Binary[T any, P interface {
+	*T
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}]() Codec[T] {
+	return Codec[T]{
+		NewEncoder: func(w io.Writer) func(T) error {
+			var n [binary.MaxVarintLen64]byte
+			return func(v T) error {
+				b, err := P(&v).MarshalBinary()
+				if err != nil {
+					return err
+				}
+				if _, err := w.Write(n[:binary.PutUvarint(n[:], uint64(len(b)))]); err != nil {
+					return err
+				}
+				_, err = w.Write(b)
+				return err
+			}
+		},
+		NewDecoder: func(r io.Reader) func() (T, error) {
+			br, ok := r.(io.ByteReader)
+			if !ok {
+				b := bufio.NewReader(r)
+				r, br = b, b
+			}
+			return func() (v T, err error) {
+				n, err := binary.ReadUvarint(br)
+				if err != nil {
+					return v, err
+				}
+				b := make([]byte, n)
+				if _, err := io.ReadFull(r, b); err != nil {
+					return v, err
+				}
+				return v, P(&v).UnmarshalBinary(b)
+			}
+		},
+	}
+}
+
+// Spill is an unbounded channel that keeps up to a budget of values in
+// memory, and spills the values beyond it to segment files on disk,
+// e.g. for an ingestion pipeline whose bursts must neither block the
+// producer nor run out of memory. The values are read back from the
+// segment files in the order in which they are sent, and each segment
+// file is deleted as soon as its values are received.
+//
+// The values are forwarded from In to Out by a goroutine, which exits
+// after the channel is closed and all values are received from Out,
+// or after CloseAndDiscard. If a segment file cannot be written or
+// read, Err returns the error, In is no longer received from, and the
+// goroutine exits after the values in memory are received. The values
+// on disk are discarded then, as their segment files may be incomplete.
+type Spill[T any] struct {
+	// The atomics are first for their 64-bit alignment.
+	len     int64 // the number of values in memory and on disk
+	spilled int64 // the number of values on disk
+
+	in      chan T
+	out     chan T
+	closing chan struct{} // closed by Close before in
+	discard chan struct{} // closed by CloseAndDiscard before closing
+	exited  chan struct{} // closed when the goroutine exits
+	dir     string        // the directory of the segment files
+	budget  int
+	codec   Codec[T]
+	leak    *record
+
+	mu          sync.Mutex
+	isClosed    bool
+	isDiscarded bool
+	err         error
+
+	// The values in memory precede the values on disk, which are only
+	// accessed by the goroutine of the channel.
+	mem  queue[T]
+	segs []*segment[T] // the oldest first, and the last may be written
+	seq  int           // the sequence number of the next segment file
+}
+
+// A segment is a file of spilled values, which is written until it
+// holds a budget of values, and then read until it is empty.
+type segment[T any] struct {
+	path string
+	n    int // the number of values that are not yet read
+	f    *os.File
+
+	w   *bufio.Writer // nil after the segment is sealed
+	enc func(T) error
+	r   *bufio.Reader // nil until the segment is read
+	dec func() (T, error)
+}
+
+// NewSpill returns a new spilling channel, which keeps up to budget
+// values in memory and writes its segment files to a new temporary
+// directory in dir, or in the default directory for temporary files if
+// dir is empty. The directory is removed when the goroutine of the
+// channel exits. Each segment file holds up to budget values.
+//
+// The budget counts values rather than bytes, as the size of a value
+// in memory is unknown to the channel; for values of different sizes,
+// it is the number of the largest values that fit into the memory.
+//
+// NewSpill panics if budget is not positive.
+func NewSpill[T any](dir string, budget int, codec Codec[T]) (*Spill[T], error) {
+	if budget <= 0 {
+		panic("chann: spill budget must be positive")
+	}
+	dir, err := os.MkdirTemp(dir, "chann-spill-")
+	if err != nil {
+		return nil, err
+	}
+	ch := &Spill[T]{
+		in:      make(chan T),
+		out:     make(chan T),
+		closing: make(chan struct{}),
+		discard: make(chan struct{}),
+		exited:  make(chan struct{}),
+		dir:     dir,
+		budget:  budget,
+		codec:   codec,
+	}
+	ch.leak = track()
+	go ch.forward()
+	return ch, nil
+}
+
+// In returns the channel to send values to. It must not be closed by
+// the built-in close; use Close instead.
+func (ch *Spill[T]) In() chan<- T { return ch.in }
+
+// Out returns the channel to receive values from.
+func (ch *Spill[T]) Out() <-chan T { return ch.out }
+
+// Close closes the channel. The values that are sent before can still
+// be received from Out, which is closed after the last of them. Like
+// the built-in close, sending to a closed channel or closing it again
+// panics.
+func (ch *Spill[T]) Close() {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.isClosed {
+		panic("chann: close of closed channel")
+	}
+	ch.close()
+}
+
+func (ch *Spill[T]) close() {
+	ch.isClosed = true
+	close(ch.closing)
+	close(ch.in)
+}
+
+// CloseAndDiscard closes the channel and discards the values in memory
+// and on disk, so that Out is closed without them. Like
+// Chan.CloseAndDiscard, it may be called after Close and more than
+// once, and returns after the goroutine of the channel has exited.
+func (ch *Spill[T]) CloseAndDiscard() {
+	ch.mu.Lock()
+	if !ch.isDiscarded {
+		ch.isDiscarded = true
+		close(ch.discard)
+	}
+	if !ch.isClosed {
+		ch.close()
+	}
+	ch.mu.Unlock()
+	<-ch.exited
+}
+
+// Len returns the number of values that are buffered in memory and on
+// disk.
+func (ch *Spill[T]) Len() int {
+	return int(atomic.LoadInt64(&ch.len))
+}
+
+// Spilled returns the number of values that are buffered on disk.
+func (ch *Spill[T]) Spilled() int {
+	return int(atomic.LoadInt64(&ch.spilled))
+}
+
+// Err returns the error of the segment file that stopped the channel,
+// or nil.
+func (ch *Spill[T]) Err() error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.err
+}
+
+// forward forwards the values from in to out through memory and disk.
+func (ch *Spill[T]) forward() {
+	for {
+		var (
+			out  chan T // nil while there is nothing to send
+			next T
+			err  error
+		)
+		if ch.mem.len() > 0 {
+			out, next = ch.out, ch.mem.peek()
+		}
+		select {
+		case out <- next:
+			err = ch.pop()
+		case v, ok := <-ch.in:
+			if !ok {
+				ch.flush()
+				return
+			}
+			err = ch.push(v)
+		}
+		if err != nil {
+			ch.fail(err)
+			return
+		}
+	}
+}
+
+// push buffers v in memory if it is within the budget and no value is
+// on disk, or writes it to the last segment file otherwise.
+func (ch *Spill[T]) push(v T) error {
+	if len(ch.segs) == 0 && ch.mem.len() < ch.budget {
+		ch.mem.push(v)
+		atomic.AddInt64(&ch.len, 1)
+		return nil
+	}
+
+	var s *segment[T]
+	if n := len(ch.segs); n > 0 && ch.segs[n-1].w != nil {
+		s = ch.segs[n-1]
+	} else {
+		s = &segment[T]{path: filepath.Join(ch.dir, fmt.Sprintf("%08d.seg", ch.seq))}
+		ch.seq++
+		f, err := os.Create(s.path)
+		if err != nil {
+			return err
+		}
+		s.f, s.w = f, bufio.NewWriter(f)
+		s.enc = ch.codec.NewEncoder(s.w)
+		ch.segs = append(ch.segs, s)
+	}
+	if err := s.enc(v); err != nil {
+		return fmt.Errorf("chann: encode to %s: %w", s.path, err)
+	}
+	s.n++
+	atomic.AddInt64(&ch.len, 1)
+	atomic.AddInt64(&ch.spilled, 1)
+	if s.n >= ch.budget {
+		return s.seal()
+	}
+	return nil
+}
+
+// pop removes the first value in memory after it is received, and
+// refills the memory from disk once it is half empty, so that the
+// segment file that is written is not sealed for every value.
+func (ch *Spill[T]) pop() error {
+	ch.mem.pop()
+	atomic.AddInt64(&ch.len, -1)
+	if ch.mem.len() > ch.budget/2 {
+		return nil
+	}
+	for ch.mem.len() < ch.budget && len(ch.segs) > 0 {
+		s := ch.segs[0]
+		if s.w != nil {
+			if err := s.seal(); err != nil {
+				return err
+			}
+		}
+		if s.r == nil {
+			f, err := os.Open(s.path)
+			if err != nil {
+				return err
+			}
+			s.f, s.r = f, bufio.NewReader(f)
+			s.dec = ch.codec.NewDecoder(s.r)
+		}
+		v, err := s.dec()
+		if err != nil {
+			return fmt.Errorf("chann: decode from %s: %w", s.path, err)
+		}
+		ch.mem.push(v)
+		s.n--
+		atomic.AddInt64(&ch.spilled, -1)
+		if s.n == 0 {
+			ch.segs = ch.segs[1:]
+			if err := s.remove(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush delivers the buffered values after in is closed, unless they
+// are discarded, and exits.
+func (ch *Spill[T]) flush() {
+	select {
+	case <-ch.closing:
+	default:
+		panic("chann: In closed by the built-in close, use Close instead")
+	}
+	for ch.mem.len() > 0 {
+		select {
+		case <-ch.discard:
+			ch.exit()
+			return
+		default:
+		}
+		select {
+		case ch.out <- ch.mem.peek():
+			if err := ch.pop(); err != nil {
+				ch.fail(err)
+				return
+			}
+		case <-ch.discard:
+		}
+	}
+	ch.exit()
+}
+
+// fail records the error that stops the channel, discards the values
+// on disk, and delivers the values in memory, unless they are
+// discarded, before it exits.
+func (ch *Spill[T]) fail(err error) {
+	ch.mu.Lock()
+	ch.err = err
+	ch.mu.Unlock()
+
+	for _, s := range ch.segs {
+		s.f.Close()
+	}
+	ch.segs = nil
+	atomic.AddInt64(&ch.len, -atomic.SwapInt64(&ch.spilled, 0))
+	for ch.mem.len() > 0 {
+		select {
+		case <-ch.discard:
+			ch.exit()
+			return
+		default:
+		}
+		select {
+		case ch.out <- ch.mem.peek():
+			ch.mem.pop()
+			atomic.AddInt64(&ch.len, -1)
+		case <-ch.discard:
+		}
+	}
+	ch.exit()
+}
+
+// exit discards the buffered values, removes the segment files and
+// closes out.
+func (ch *Spill[T]) exit() {
+	for _, s := range ch.segs {
+		s.f.Close()
+	}
+	os.RemoveAll(ch.dir)
+	ch.mem = queue[T]{}
+	ch.segs = nil
+	atomic.StoreInt64(&ch.len, 0)
+	atomic.StoreInt64(&ch.spilled, 0)
+	untrack(ch.leak)
+	close(ch.out)
+	close(ch.exited)
+}
+
+// seal flushes and closes the segment file after it is written.
+func (s *segment[T]) seal() error {
+	err := s.w.Flush()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	s.f, s.w, s.enc = nil, nil, nil
+	return err
+}
+
+// remove closes and deletes the segment file after it is read.
+func (s *segment[T]) remove() error {
+	s.f.Close()
+	return os.Remove(s.path)
+}
@@ -0,0 +1,195 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"ultimate-chan/chann"
+)
+
+// segments returns the number of segment files of the spilling
+// channels in dir.
+func segments(t *testing.T, dir string) int {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.seg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(files)
+}
+
+func TestSpill(t *testing.T) {
+	const n = 100
+	dir := t.TempDir()
+	ch, err := chann.NewSpill[int](dir, 8, chann.Gob[int]())
+	if err != nil {
+		t.Fatalf("NewSpill: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		ch.In() <- i
+	}
+	for ch.Spilled() < n-8 { // until the goroutine writes the last value
+		runtime.Gosched()
+	}
+	if got := ch.Len(); got != n {
+		t.Fatalf("Len: got %d, want %d", got, n)
+	}
+	if got := ch.Spilled(); got != n-8 {
+		t.Fatalf("Spilled: got %d, want %d", got, n-8)
+	}
+	if got, want := segments(t, dir), (n-8+7)/8; got != want {
+		t.Fatalf("segments: got %d, want %d", got, want)
+	}
+
+	// The values are received in order while more are sent.
+	for i := 0; i < n/2; i++ {
+		if v := <-ch.Out(); v != i {
+			t.Fatalf("Out: got %d, want %d", v, i)
+		}
+	}
+	if got := segments(t, dir); got >= (n-8+7)/8 {
+		t.Fatalf("segments: got %d after receiving, want fewer", got)
+	}
+	for i := n; i < 2*n; i++ {
+		ch.In() <- i
+	}
+	ch.Close()
+	want := n / 2
+	for v := range ch.Out() {
+		if v != want {
+			t.Fatalf("Out: got %d, want %d", v, want)
+		}
+		want++
+	}
+	if want != 2*n {
+		t.Fatalf("Out: got %d values, want %d", want, 2*n)
+	}
+	if err := ch.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("the directory of the segment files is not removed: %v", entries)
+	}
+}
+
+func TestSpillBinary(t *testing.T) {
+	ch, err := chann.NewSpill[time.Time](t.TempDir(), 1, chann.Binary[time.Time]())
+	if err != nil {
+		t.Fatalf("NewSpill: %v", err)
+	}
+	now := time.Now()
+	want := []time.Time{now, now.Add(time.Second), now.Add(time.Minute)}
+	for _, v := range want {
+		ch.In() <- v
+	}
+	ch.Close()
+	i := 0
+	for v := range ch.Out() {
+		if !v.Equal(want[i]) {
+			t.Fatalf("Out: got %v, want %v", v, want[i])
+		}
+		i++
+	}
+	if i != len(want) {
+		t.Fatalf("Out: got %d values, want %d", i, len(want))
+	}
+}
+
+func TestSpillConcurrent(t *testing.T) {
+	const n = 10000
+	ch, err := chann.NewSpill[int](t.TempDir(), 16, chann.Gob[int]())
+	if err != nil {
+		t.Fatalf("NewSpill: %v", err)
+	}
+	go func() {
+		for i := 0; i < n; i++ {
+			ch.In() <- i
+		}
+		ch.Close()
+	}()
+	want := 0
+	for v := range ch.Out() {
+		if v != want {
+			t.Fatalf("Out: got %d, want %d", v, want)
+		}
+		want++
+	}
+	if want != n {
+		t.Fatalf("Out: got %d values, want %d", want, n)
+	}
+}
+
+func TestSpillCloseAndDiscard(t *testing.T) {
+	dir := t.TempDir()
+	ch, err := chann.NewSpill[int](dir, 4, chann.Gob[int]())
+	if err != nil {
+		t.Fatalf("NewSpill: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		ch.In() <- i
+	}
+	ch.CloseAndDiscard()
+	ch.CloseAndDiscard()
+	if _, ok := <-ch.Out(); ok {
+		t.Fatalf("Out: got a value after CloseAndDiscard")
+	}
+	if n := ch.Len(); n != 0 {
+		t.Fatalf("Len: got %d, want 0", n)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("the directory of the segment files is not removed: %v", entries)
+	}
+}
+
+func TestSpillError(t *testing.T) {
+	errFull := errors.New("disk full")
+	codec := chann.Gob[int]()
+	codec.NewEncoder = func(io.Writer) func(int) error {
+		return func(int) error { return errFull }
+	}
+	ch, err := chann.NewSpill[int](t.TempDir(), 2, codec)
+	if err != nil {
+		t.Fatalf("NewSpill: %v", err)
+	}
+	ch.In() <- 1
+	ch.In() <- 2
+	ch.In() <- 3 // cannot be spilled
+
+	// The values in memory are still delivered.
+	got := []int{}
+	for v := range ch.Out() {
+		got = append(got, v)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Fatalf("Out: got %v, want [1 2]", got)
+	}
+	if err := ch.Err(); !errors.Is(err, errFull) {
+		t.Fatalf("Err: got %v, want %v", err, errFull)
+	}
+	ch.CloseAndDiscard()
+}
+
+func TestSpillBudget(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("NewSpill: want a panic for a non-positive budget")
+		}
+	}()
+	chann.NewSpill[int](t.TempDir(), 0, chann.Gob[int]())
+}
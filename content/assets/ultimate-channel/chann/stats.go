@@ -0,0 +1,145 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann
+
+import (
+	"expvar"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// vars publishes the stats of the channels with a Name, e.g. at
+// /debug/vars of a server that imports expvar.
+var vars = expvar.NewMap("chann")
+
+// names are the publications of the channels by name, the latest last,
+// of which only the latest is published.
+var names struct {
+	sync.Mutex
+	pubs map[string][]*publication
+}
+
+// A publication is the stats of a channel with a Name.
+type publication struct {
+	name string
+	v    expvar.Var
+}
+
+// publish publishes v as the expvar chann.<name>, in place of the
+// channel that is published under the same name.
+func publish(name string, v expvar.Var) *publication {
+	names.Lock()
+	defer names.Unlock()
+	if names.pubs == nil {
+		names.pubs = map[string][]*publication{}
+	}
+	p := &publication{name, v}
+	names.pubs[name] = append(names.pubs[name], p)
+	vars.Set(name, v)
+	return p
+}
+
+// unpublish removes the publication, and publishes the latest of the
+// remaining channels with the same name again, if any.
+func (p *publication) unpublish() {
+	names.Lock()
+	defer names.Unlock()
+	pubs := names.pubs[p.name]
+	for i := range pubs {
+		if pubs[i] == p {
+			pubs = append(pubs[:i], pubs[i+1:]...)
+			break
+		}
+	}
+	if len(pubs) == 0 {
+		delete(names.pubs, p.name)
+		vars.Delete(p.name)
+		return
+	}
+	names.pubs[p.name] = pubs
+	vars.Set(p.name, pubs[len(pubs)-1].v)
+}
+
+// Stats are the statistics of a channel. A channel without a goroutine
+// only counts its Len, as its values are not observed by the package.
+//
+// The values that are sent to a channel are buffered until they are
+// received, discarded by its overflow policy, or discarded when the
+// channel is abandoned, so that Enqueued is Dequeued plus Dropped plus
+// Len until then.
+type Stats struct {
+	Len       int           // the number of buffered values
+	HighWater int           // the highest Len so far
+	Enqueued  int64         // the number of values sent
+	Dequeued  int64         // the number of values received
+	Dropped   int64         // the number of values discarded by the overflow policy
+	Wait      time.Duration // the total time that the received values were buffered
+}
+
+// Stats returns the statistics of the channel. Like Len, the result
+// may be out of date as soon as it is returned if the channel is used
+// concurrently.
+func (ch *Chan[T]) Stats() Stats {
+	if !ch.forwarded() {
+		return Stats{Len: len(ch.in)}
+	}
+	return Stats{
+		Len:       int(atomic.LoadInt64(&ch.len)),
+		HighWater: int(atomic.LoadInt64(&ch.highWater)),
+		Enqueued:  atomic.LoadInt64(&ch.enqueued),
+		Dequeued:  atomic.LoadInt64(&ch.dequeued),
+		Dropped:   atomic.LoadInt64(&ch.dropped),
+		Wait:      time.Duration(atomic.LoadInt64(&ch.wait)),
+	}
+}
+
+// Name publishes the Stats of a channel as the expvar chann.<name>
+// until its goroutine exits. A buffered channel with a name has a
+// goroutine to count its values; an unbuffered channel cannot be named.
+//
+// If several channels have the same name, e.g. as a channel is replaced
+// before the old one exits, only the one that is created last is
+// published. When it exits, the latest of the others is published
+// again.
+func Name(name string) Opt {
+	return func(c *config) { c.name = name }
+}
+
+// SoftLimit reports the Stats of a channel when its length reaches n,
+// and again whenever the length doubles, until it falls below n. It is
+// an early warning of a channel that grows without bound, e.g. as the
+// receiver is stuck. The report calls f on the goroutine of the
+// channel, which must not block, or logs the stats if f is nil.
+//
+// A buffered channel with a soft limit has a goroutine to count its
+// values; an unbuffered channel cannot have a soft limit. SoftLimit
+// panics if n is not positive.
+func SoftLimit(n int, f func(Stats)) Opt {
+	if n <= 0 {
+		panic("chann: soft limit must be positive")
+	}
+	return func(c *config) {
+		c.softLimit = n
+		c.onSoftLimit = f
+	}
+}
+
+func (ch *Chan[T]) reportSoftLimit() {
+	s := ch.Stats()
+	if ch.cfg.onSoftLimit != nil {
+		ch.cfg.onSoftLimit(s)
+		return
+	}
+	name := ch.cfg.name
+	if name == "" {
+		name = "unnamed"
+	}
+	log.Printf("chann: channel %s has %d buffered values, above its soft limit of %d: %+v",
+		name, s.Len, ch.cfg.softLimit, s)
+}
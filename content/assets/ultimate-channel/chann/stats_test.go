@@ -0,0 +1,116 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package chann_test
+
+import (
+	"expvar"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"ultimate-chan/chann"
+)
+
+func TestStats(t *testing.T) {
+	reports := []int{}
+	ch := chann.New[int](chann.SoftLimit(4, func(s chann.Stats) {
+		reports = append(reports, s.Len)
+	}))
+	send := func(n int) {
+		for i := 0; i < n; i++ {
+			ch.In() <- i
+		}
+	}
+	receive := func(n int) {
+		for i := 0; i < n; i++ {
+			<-ch.Out()
+		}
+	}
+	send(20)
+	receive(20)
+	send(4) // the soft limit is reported again
+	ch.Close()
+	receive(4)
+	if _, ok := <-ch.Out(); ok {
+		t.Fatalf("Out: not closed")
+	}
+
+	s := ch.Stats()
+	if s.Len != 0 || s.HighWater != 20 || s.Enqueued != 24 || s.Dequeued != 24 || s.Dropped != 0 || s.Wait <= 0 {
+		t.Fatalf("Stats: got %+v", s)
+	}
+	if want := []int{4, 8, 16, 4}; !reflect.DeepEqual(reports, want) {
+		t.Fatalf("SoftLimit: got reports at %v, want %v", reports, want)
+	}
+}
+
+func TestName(t *testing.T) {
+	ch := chann.New[int](chann.Cap(2), chann.Name("test"))
+	ch.In() <- 1
+	ch.In() <- 2
+	for ch.Len() < 2 { // until the goroutine buffers 2
+		runtime.Gosched()
+	}
+	select {
+	case ch.In() <- 3:
+		t.Fatalf("In: sent to a full channel")
+	default:
+	}
+
+	v := expvar.Get("chann").(*expvar.Map).Get("test")
+	if v == nil || !strings.Contains(v.String(), `"Len":2`) {
+		t.Fatalf("expvar chann.test: got %v", v)
+	}
+	ch.Close()
+	for range ch.Out() {
+	}
+	if v := expvar.Get("chann").(*expvar.Map).Get("test"); v != nil {
+		t.Fatalf("expvar chann.test: got %v after the channel exited", v)
+	}
+}
+
+func TestStatsUnbuffered(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("New: no panic")
+		}
+	}()
+	chann.New[int](chann.Cap(0), chann.Name("unbuffered"))
+}
+
+func TestNameDuplicate(t *testing.T) {
+	get := func() string {
+		if v := expvar.Get("chann").(*expvar.Map).Get("dup"); v != nil {
+			return v.String()
+		}
+		return ""
+	}
+	ch1 := chann.New[int](chann.Name("dup"))
+	ch2 := chann.New[int](chann.Name("dup"))
+	ch2.In() <- 1
+	for ch2.Len() < 1 {
+		runtime.Gosched()
+	}
+	if v := get(); !strings.Contains(v, `"Len":1`) {
+		t.Fatalf("expvar chann.dup: got %v, want the stats of the latest channel", v)
+	}
+
+	ch2.CloseAndDiscard()
+	if v := get(); !strings.Contains(v, `"Len":0`) {
+		t.Fatalf("expvar chann.dup: got %v, want the stats of the remaining channel", v)
+	}
+	ch3 := chann.New[int](chann.Name("dup"))
+	ch1.CloseAndDiscard() // does not delete the publication of ch3
+	if v := get(); v == "" {
+		t.Fatalf("expvar chann.dup: deleted while a channel is still named")
+	}
+	ch3.CloseAndDiscard()
+	if v := get(); v != "" {
+		t.Fatalf("expvar chann.dup: got %v after all channels exited", v)
+	}
+}
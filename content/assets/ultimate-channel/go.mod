@@ -1,3 +1,3 @@
 module ultimate-chan
 
-go 1.17
\ No newline at end of file
+go 1.18
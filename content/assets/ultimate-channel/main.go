@@ -4,12 +4,15 @@
 //
 // Written by Changkun Ou <changkun.de>
 
-// WARNING: This example contains a deadlock.
+// WARNING: This example contains a deadlock, unless it runs with
+// -solution=1 or -solution=2.
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math/rand"
+	"os"
 	"time"
 )
 
@@ -28,12 +31,65 @@ func (p *renderProfile) Draw() interface{} {
 	return fmt.Sprintf("draw-%d-%dx%d", p.id, p.width, p.height)
 }
 
+// solution selects a fix of the deadlock, or none.
+type solution int
+
+const (
+	noSolution     solution = iota
+	selectDefault           // Solution 1
+	unboundedChann          // Solution 2
+)
+
+// app is the rendering and the event thread of the example. Its clock
+// and random source are injected, and its threads call hooks around
+// their sends, so that a test can drive the threads into the deadlock
+// deterministically and observe it.
+type app struct {
+	solution solution
+	ticks    <-chan time.Time     // the events, e.g. of a time.Ticker
+	random   func() float64       // e.g. rand.Float64
+	flush    func(id interface{}) // flushes a draw call to the display
+
+	// send is called before a thread sends to the channel of the given
+	// name, i.e. "draw", "drawIn" of Solution 2, or "change". If the send
+	// cannot proceed immediately, block is called before the thread
+	// blocks on it, and unblock after the value is sent.
+	send, block, unblock func(name string)
+}
+
 func main() {
+	n := flag.Int("solution", 0, "the solution of the deadlock: 0 (none), 1 or 2")
+	flag.Parse()
+	if *n < 0 || *n > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	event := time.NewTicker(100 * time.Millisecond)
+	defer event.Stop()
+	a := &app{
+		solution: solution(*n),
+		ticks:    event.C,
+		random:   rand.Float64,
+		flush:    func(id interface{}) { println(id) },
+		send:     func(string) {},
+		block:    func(string) {},
+		unblock:  func(string) {},
+	}
+	a.run(nil)
+}
+
+// run runs the threads until done is closed, which also unblocks their
+// sends, and returns after both threads have exited.
+func (a *app) run(done <-chan struct{}) {
 	// draw is a channel for receiving finished draw calls.
 	draw := make(chan interface{})
 
 	// Solution 2 (step 1):
-	// drawIn, drawOut := MakeChan()
+	drawIn, drawOut := (chan<- interface{})(draw), (<-chan interface{})(draw)
+	if a.solution == unboundedChann {
+		drawIn, drawOut = MakeChan()
+	}
 
 	// change is a channel to receive notification of the change of
 	// rendering settings.
@@ -46,7 +102,9 @@ func main() {
 	// rendering setting id. If there is a change of rendering setting,
 	// the event thread notifies the rendering setting change, and here
 	// increases the rendering setting id.
+	rendered := make(chan struct{})
 	go func() {
+		defer close(rendered)
 		p := &renderProfile{id: 0, width: 800, height: 500}
 		for {
 			select {
@@ -55,15 +113,28 @@ func main() {
 				p.id++
 				p.width = size.width
 				p.height = size.height
+			case <-done:
+				return
 			default:
-				draw <- p.Draw()
-				// Solution 1:
-				// select {
-				// case draw <- p.Draw():
-				// default:
-				// }
-				// Solution 2 (step 2):
-				// drawIn <- p.Draw()
+				switch a.solution {
+				case noSolution:
+					if !a.sendDraw("draw", draw, p.Draw(), done) {
+						return
+					}
+				case selectDefault:
+					// Solution 1:
+					id := p.Draw()
+					a.send("draw")
+					select {
+					case draw <- id:
+					default:
+					}
+				case unboundedChann:
+					// Solution 2 (step 2):
+					if !a.sendDraw("drawIn", drawIn, p.Draw(), done) {
+						return
+					}
+				}
 			}
 		}
 	}()
@@ -72,21 +143,65 @@ func main() {
 	//
 	// Process events every 100 ms. Otherwise, process drawcall request
 	// upon-avaliable.
-	event := time.NewTicker(100 * time.Millisecond)
 	for {
 		select {
-		case id := <-draw:
-			// Solution 2 (step 3):
-			// case id := <-drawOut:
-			println(id)
-		case <-event.C:
+		// Solution 2 (step 3): drawOut is the receiver of MakeChan.
+		case id := <-drawOut:
+			a.flush(id)
+		case <-a.ticks:
 			// Notify the rendering thread there is a change regarding
 			// rendering settings. We simulate a random size at every
 			// event processing loop.
-			change <- ResizeEvent{
-				width:  int(rand.Float64() * 100),
-				height: int(rand.Float64() * 100),
+			size := ResizeEvent{
+				width:  int(a.random() * 100),
+				height: int(a.random() * 100),
+			}
+			a.send("change")
+			select {
+			case change <- size:
+			default:
+				a.block("change")
+				select {
+				case change <- size:
+				case <-done:
+					a.stop(rendered, drawIn, drawOut)
+					return
+				}
+				a.unblock("change")
 			}
+		case <-done:
+			a.stop(rendered, drawIn, drawOut)
+			return
+		}
+	}
+}
+
+// sendDraw sends the draw call id to the draw channel of the given name
+// unless done is closed, and reports whether it is sent.
+func (a *app) sendDraw(name string, draw chan<- interface{}, id interface{}, done <-chan struct{}) bool {
+	a.send(name)
+	select {
+	case draw <- id:
+		return true
+	default:
+	}
+	a.block(name)
+	defer a.unblock(name)
+	select {
+	case draw <- id:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// stop waits for the rendering thread to exit, and for the goroutine of
+// MakeChan of Solution 2, which exits after its buffer is received.
+func (a *app) stop(rendered <-chan struct{}, drawIn chan<- interface{}, drawOut <-chan interface{}) {
+	<-rendered
+	if a.solution == unboundedChann {
+		close(drawIn)
+		for range drawOut {
 		}
 	}
 }
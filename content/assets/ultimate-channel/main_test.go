@@ -0,0 +1,121 @@
+// Copyright 2021 The golang.design Initiative Authors.
+// All rights reserved. Use of this source code is governed
+// by a MIT license that can be found in the LICENSE file.
+//
+// Written by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestDeadlock reproduces the deadlock of the example deterministically,
+// and verifies that each solution eliminates it.
+//
+// The test schedules the threads as follows: the rendering thread
+// pauses before it sends its second draw call, and the clock ticks
+// meanwhile, so that the event thread takes the tick instead of a draw
+// call. After the event thread is about to send the resize event, the
+// rendering thread proceeds to send its draw call. Without a solution,
+// both threads block on their sends then: the event thread only
+// receives draw calls in its select, and the rendering thread only
+// receives resize events in its select, thus the deadlock is certain as
+// soon as both threads are blocked on these sends. Solution 1 sends to
+// the same draw channel, and must never block on it. Solution 2 sends
+// to drawIn instead, which is received by the goroutine of MakeChan
+// rather than the event thread, thus the rendering thread may block on
+// it for a moment without a deadlock. If the resize event is not
+// handled in time, the test fails.
+func TestDeadlock(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		solution solution
+		deadlock bool
+	}{
+		{"none", noSolution, true},
+		{"select-default", selectDefault, false},
+		{"unbounded-chann", unboundedChann, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				ticks      = make(chan time.Time, 1) // the clock
+				changing   = make(chan struct{})     // the resize event is about to be sent
+				deadlocked = make(chan struct{})     // both threads are blocked on their sends
+				resized    = make(chan struct{})     // a draw call after the event is flushed
+				done       = make(chan struct{})
+				stopped    = make(chan struct{})
+
+				mu      sync.Mutex
+				blocked = map[string]bool{}
+				stuck   = false // deadlocked is closed
+				draws   = 0
+			)
+			a := &app{
+				solution: tt.solution,
+				ticks:    ticks,
+				random:   func() float64 { return 0.5 },
+				flush: func(id interface{}) {
+					if id == "draw-1-50x50" && resized != nil {
+						close(resized)
+						resized = nil
+					}
+				},
+				send: func(name string) {
+					switch name {
+					case "change":
+						close(changing)
+					case "draw", "drawIn":
+						draws++
+						if draws == 2 {
+							ticks <- time.Time{}
+							<-changing
+						}
+					}
+				},
+				block: func(name string) {
+					mu.Lock()
+					defer mu.Unlock()
+					blocked[name] = true
+					if blocked["draw"] && blocked["change"] && !stuck {
+						stuck = true
+						close(deadlocked)
+					}
+				},
+				unblock: func(name string) {
+					mu.Lock()
+					defer mu.Unlock()
+					delete(blocked, name)
+				},
+			}
+			wait := resized
+			go func() {
+				a.run(done)
+				close(stopped)
+			}()
+			defer func() {
+				close(done)
+				select {
+				case <-stopped:
+				case <-time.After(10 * time.Second):
+					t.Errorf("the threads do not stop")
+				}
+			}()
+
+			select {
+			case <-wait:
+				if tt.deadlock {
+					t.Fatalf("the resize event is handled, want a deadlock")
+				}
+			case <-deadlocked:
+				if !tt.deadlock {
+					t.Fatalf("both threads are blocked on their sends, want no deadlock")
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatalf("neither the resize event is handled nor both threads are blocked")
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@
 //
 // The code below is produced by Changkun Ou <hi@changkun.de>.
 
+//research:requires display
+
 package app
 
 import (
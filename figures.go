@@ -0,0 +1,69 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"strings"
+
+	"golang.design/x/research/internal/post"
+)
+
+// figures replaces the standalone images of the body by LaTeX figures,
+// and reports whether there are any. The alternative text of an image
+// is attached to the figure as a tooltip, which screen readers announce,
+// and the title of an image becomes the caption of the figure as on the
+// website. The image itself is kept in markdown, so that pandoc resolves
+// its path relative to the post.
+func figures(body string) (string, bool) {
+	found := false
+	body = replaceImages(body, func(img post.Image) string {
+		found = true
+		fig := "![](" + img.Src + ")"
+		if img.Alt != "" {
+			fig = "`\\pdftooltip{`{=latex}" + fig + "`}{" + texText(img.Alt) + "}`{=latex}"
+		}
+		caption := ""
+		if img.Title != "" {
+			caption = "\\caption{" + texText(img.Title) + "}\n"
+		}
+		return "\n```{=latex}\n\\begin{figure}[htbp]\n\\centering\n```\n\n" + fig +
+			"\n\n```{=latex}\n" + caption + "\\end{figure}\n```\n"
+	})
+	return body, found
+}
+
+// replaceImages replaces the standalone images of the markdown s by
+// f(img). Images inside of code are left unchanged.
+func replaceImages(s string, f func(img post.Image) string) string {
+	b := []byte(s)
+	var w strings.Builder
+	last := 0
+	for _, img := range post.Images(b) {
+		if !img.Standalone(b) {
+			continue
+		}
+		w.WriteString(s[last:img.Start])
+		w.WriteString(f(img))
+		last = img.Stop
+	}
+	w.WriteString(s[last:])
+	return w.String()
+}
+
+// tooltip attaches the alternative text alt to the LaTeX content tex.
+func tooltip(tex, alt string) string {
+	if alt == "" {
+		return tex
+	}
+	return "\\pdftooltip{" + tex + "}{" + texText(alt) + "}"
+}
+
+// texText escapes the characters of the plain text s that are special
+// in LaTeX, e.g. in captions, table cells and option values.
+func texText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\textbackslash{}`, `{`, `\{`, `}`, `\}`, `$`, `\$`, `&`, `\&`,
+		`#`, `\#`, `^`, `\textasciicircum{}`, `_`, `\_`, `~`, `\textasciitilde{}`, `%`, `\%`,
+	).Replace(s)
+}
@@ -0,0 +1,231 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+// Package benchchart draws charts of the benchmark results of the
+// companion code of golang.design research posts.
+//
+// A post embeds a chart with the benchchart shortcode, e.g.
+//
+//	{{< benchchart name="pointer-params-fields" type="line"
+//	    files="inline=pointer-params/fields/inline.txt noinline=pointer-params/fields/noinline.txt"
+//	    match="Vec/(?P<series>add[vp])-s(?P<x>\d+)"
+//	    title="addv v.s. addp with different number of fields"
+//	    xlabel="number of fields" >}}
+//
+// The parameters are:
+//
+//	name    the name of the chart, which must be unique in the site
+//	files   the benchmark files, as the arguments of the benchtable
+//	        shortcode, separated by spaces
+//	match   a regular expression that selects the benchmarks by name;
+//	        its named groups "series" and "x" determine the series and
+//	        the x value of a benchmark (default: all benchmarks in one
+//	        series with their names as x values)
+//	type    line or bar (default bar)
+//	unit    the unit of the values (default ns/op)
+//	title   the title of the chart
+//	alt     the alternative text of the chart for screen readers
+//	        (default: the title)
+//	xlabel  the label of the x-axis
+//
+// Each point is the median of the results of a benchmark, and its error
+// bar spans the results without outliers, as summarized by benchstat.
+// If there are multiple files, the series of each file are labeled by
+// the label of the file.
+//
+// The charts are drawn as SVG for the website, where research charts
+// writes them to static/charts/<name>.svg, and as TikZ for pdfgen.
+package benchchart
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.design/x/research/internal/benchstat"
+)
+
+// Dir is the directory relative to the site root that contains the
+// SVG charts.
+const Dir = "static/charts"
+
+// A Shortcode is a use of the benchchart shortcode in a post.
+type Shortcode struct {
+	Text   string            // the text of the shortcode
+	Params map[string]string // the named parameters
+}
+
+var (
+	reShortcode = regexp.MustCompile(`(?s)\{\{<\s*benchchart\s+(.*?)\s*>\}\}`)
+	reParam     = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+// Shortcodes returns the benchchart shortcodes in a post.
+func Shortcodes(b []byte) []Shortcode {
+	scs := []Shortcode{}
+	for _, m := range reShortcode.FindAllSubmatch(b, -1) {
+		sc := Shortcode{Text: string(m[0]), Params: map[string]string{}}
+		for _, p := range reParam.FindAllSubmatch(m[1], -1) {
+			sc.Params[string(p[1])] = string(p[2])
+		}
+		scs = append(scs, sc)
+	}
+	return scs
+}
+
+// A Chart is a chart of benchmark results.
+type Chart struct {
+	Name   string
+	Title  string
+	Type   string // line or bar
+	XLabel string
+	YLabel string
+	Series []*Series
+	XS     []string // the x values in the order of their first appearance
+
+	numeric bool // if all x values are numbers
+}
+
+// A Series is a series of points of a chart.
+type Series struct {
+	Name   string
+	Points map[string]Point // by x value
+}
+
+// A Point is the summary of the results of a benchmark, in the scaled
+// unit of the chart.
+type Point struct {
+	Median float64
+	Lo, Hi float64 // the range of the results without outliers
+}
+
+// Build builds the chart of a shortcode from the benchmark files in the
+// site at root.
+func Build(root string, sc Shortcode) (*Chart, error) {
+	p := sc.Params
+	c := &Chart{
+		Name:   p["name"],
+		Title:  p["title"],
+		Type:   p["type"],
+		XLabel: p["xlabel"],
+	}
+	if c.Name == "" {
+		return nil, fmt.Errorf("benchchart: missing name")
+	}
+	if c.Type == "" {
+		c.Type = "bar"
+	}
+	if c.Type != "bar" && c.Type != "line" {
+		return nil, fmt.Errorf("benchchart %v: unknown type %q", c.Name, c.Type)
+	}
+	unit := p["unit"]
+	if unit == "" {
+		unit = "ns/op"
+	}
+	match := regexp.MustCompile(".*")
+	if p["match"] != "" {
+		var err error
+		if match, err = regexp.Compile(p["match"]); err != nil {
+			return nil, fmt.Errorf("benchchart %v: %v", c.Name, err)
+		}
+	}
+
+	inputs := []benchstat.Input{}
+	for _, arg := range strings.Fields(p["files"]) {
+		inputs = append(inputs, benchstat.ParseInput(arg))
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("benchchart %v: missing files", c.Name)
+	}
+	files, err := benchstat.Load(root, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("benchchart %v: %v", c.Name, err)
+	}
+
+	// Collect the values by series and x value.
+	values := map[string]map[string][]float64{}
+	series := map[string]*Series{}
+	for _, f := range files {
+		for _, r := range f.Results {
+			v, ok := r.Values[unit]
+			m := match.FindStringSubmatch(r.Name)
+			if !ok || m == nil {
+				continue
+			}
+			name, x := "", m[0]
+			if i := match.SubexpIndex("series"); i > 0 {
+				name = m[i]
+			}
+			if i := match.SubexpIndex("x"); i > 0 {
+				x = m[i]
+			}
+			if len(files) > 1 {
+				name = strings.TrimSpace(name + " " + f.Label)
+			}
+			if series[name] == nil {
+				series[name] = &Series{Name: name, Points: map[string]Point{}}
+				values[name] = map[string][]float64{}
+				c.Series = append(c.Series, series[name])
+			}
+			if !contains(c.XS, x) {
+				c.XS = append(c.XS, x)
+			}
+			values[name][x] = append(values[name][x], v)
+		}
+	}
+	if len(c.Series) == 0 {
+		return nil, fmt.Errorf("benchchart %v: no results of %v match %q", c.Name, unit, match)
+	}
+
+	peak := 0.0
+	for _, s := range c.Series {
+		for x, v := range values[s.Name] {
+			st := benchstat.Summarize(v)
+			s.Points[x] = Point{Median: st.Median, Lo: st.Values[0], Hi: st.Values[len(st.Values)-1]}
+			peak = math.Max(peak, st.Median)
+		}
+	}
+	factor, suffix := benchstat.Scale(peak, unit)
+	for _, s := range c.Series {
+		for x, pt := range s.Points {
+			s.Points[x] = Point{Median: pt.Median / factor, Lo: pt.Lo / factor, Hi: pt.Hi / factor}
+		}
+	}
+	c.YLabel = benchstat.Quantity(unit)
+	if suffix != "" {
+		c.YLabel += " (" + suffix + ")"
+	}
+
+	c.numeric = true
+	for _, x := range c.XS {
+		if _, err := strconv.ParseFloat(x, 64); err != nil {
+			c.numeric = false
+		}
+	}
+	return c, nil
+}
+
+// SVG returns the chart as an SVG image.
+func (c *Chart) SVG() []byte {
+	s := &svg{}
+	c.draw(s)
+	return s.bytes()
+}
+
+// TikZ returns the chart as a TikZ picture. It requires the tikz package.
+func (c *Chart) TikZ() string {
+	t := &tikz{}
+	c.draw(t)
+	return t.String()
+}
+
+func contains(s []string, x string) bool {
+	for _, v := range s {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package benchchart
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const inlineTxt = `goos: linux
+goarch: amd64
+pkg: pparam/fields
+BenchmarkVec/addv-s0-16    1000000000    0.25 ns/op
+BenchmarkVec/addv-s0-16    1000000000    0.24 ns/op
+BenchmarkVec/addv-s0-16    1000000000    0.26 ns/op
+BenchmarkVec/addp-s0-16    1000000000    2.20 ns/op
+BenchmarkVec/addp-s0-16    1000000000    2.21 ns/op
+BenchmarkVec/addv-s1-16    1000000000    0.49 ns/op
+BenchmarkVec/addp-s1-16    1000000000    2.20 ns/op
+BenchmarkOther-16          1000000000    1.00 ns/op
+`
+
+func TestShortcodes(t *testing.T) {
+	post := "Text.\n\n" + `{{< benchchart name="fields" type="line"
+    files="inline=fields/inline.txt"
+    match="Vec/(?P<series>add[vp])-s(?P<x>\d+)" >}}` + "\n"
+	got := Shortcodes([]byte(post))
+	if len(got) != 1 {
+		t.Fatalf("Shortcodes: got %d shortcodes, want 1", len(got))
+	}
+	want := map[string]string{
+		"name":  "fields",
+		"type":  "line",
+		"files": "inline=fields/inline.txt",
+		"match": `Vec/(?P<series>add[vp])-s(?P<x>\d+)`,
+	}
+	if !reflect.DeepEqual(got[0].Params, want) {
+		t.Fatalf("Shortcodes: got %v, want %v", got[0].Params, want)
+	}
+	if !strings.HasPrefix(post[strings.Index(post, got[0].Text):], "{{< benchchart") {
+		t.Fatalf("Shortcodes: got text %q", got[0].Text)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "content", "assets", "fields", "inline.txt")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(inlineTxt), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Build(root, Shortcode{Params: map[string]string{
+		"name":  "fields",
+		"type":  "line",
+		"files": "fields/inline.txt",
+		"match": `Vec/(?P<series>add[vp])-s(?P<x>\d+)`,
+	}})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(c.Series) != 2 || c.Series[0].Name != "addv" || c.Series[1].Name != "addp" {
+		t.Fatalf("Build: got series %+v", c.Series)
+	}
+	if !reflect.DeepEqual(c.XS, []string{"0", "1"}) || !c.numeric {
+		t.Fatalf("Build: got x values %v", c.XS)
+	}
+	if p := c.Series[0].Points["0"]; p.Median != 0.25 || p.Lo != 0.24 || p.Hi != 0.26 {
+		t.Fatalf("Build: got point %+v", p)
+	}
+	if c.YLabel != "time/op (ns)" {
+		t.Fatalf("Build: got y label %q", c.YLabel)
+	}
+
+	svg := string(c.SVG())
+	if !strings.HasPrefix(svg, "<svg ") || strings.Count(svg, "<polyline") != 2 || !strings.Contains(svg, ">addp</text>") {
+		t.Fatalf("SVG: got\n%s", svg)
+	}
+	tikz := c.TikZ()
+	if !strings.HasPrefix(tikz, `\begin{tikzpicture}`) || !strings.Contains(tikz, "{\\scriptsize addp}") {
+		t.Fatalf("TikZ: got\n%s", tikz)
+	}
+
+	if _, err := Build(root, Shortcode{Params: map[string]string{
+		"name":  "none",
+		"files": "fields/inline.txt",
+		"match": "Missing",
+	}}); err == nil {
+		t.Fatalf("Build: expect an error if no benchmark matches")
+	}
+}
+
+func TestTicks(t *testing.T) {
+	tests := []struct {
+		lo, hi float64
+		want   string
+	}{
+		{0, 16.6, "0 5 10 15 20"},
+		{0, 9, "0 2 4 6 8 10"},
+		{0, 0.5, "0 0.1 0.2 0.3 0.4 0.5"},
+		{0, 0, "0 0.2 0.4 0.6 0.8 1"},
+		{1200, 4800, "1000 2000 3000 4000 5000"},
+	}
+	for _, tt := range tests {
+		ts := []string{}
+		for _, v := range ticks(tt.lo, tt.hi) {
+			ts = append(ts, formatTick(v))
+		}
+		if got := strings.Join(ts, " "); got != tt.want {
+			t.Errorf("ticks(%v, %v): got %v, want %v", tt.lo, tt.hi, got, tt.want)
+		}
+	}
+}
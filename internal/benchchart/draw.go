@@ -0,0 +1,301 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package benchchart
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The layout of a chart in pixels.
+const (
+	width    = 640
+	height   = 400
+	left     = 64  // the space for the y-axis
+	right    = 160 // the space for the legend
+	top      = 48  // the space for the title
+	bottom   = 56  // the space for the x-axis
+	fontSize = 12
+)
+
+// palette are the colors of the series.
+var palette = []string{"#4285f4", "#ea4335", "#fbbc04", "#34a853", "#00add8", "#ce3262", "#8e44ad", "#7f8c8d"}
+
+// A canvas is a drawing surface with the origin at the top left.
+type canvas interface {
+	line(x1, y1, x2, y2 float64, color string, width float64)
+	polyline(pts [][2]float64, color string, width float64)
+	rect(x, y, w, h float64, color string)
+	circle(x, y, r float64, color string)
+	// text draws s anchored at start, middle or end, optionally rotated
+	// by 90 degrees counterclockwise.
+	text(x, y float64, s, anchor string, size float64, rotate bool)
+}
+
+// draw draws the chart to a canvas.
+func (c *Chart) draw(cv canvas) {
+	x0, x1 := float64(left), float64(width-right)
+	y0, y1 := float64(top), float64(height-bottom)
+
+	// The y-axis starts at zero.
+	hi := 0.0
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			hi = math.Max(hi, p.Hi)
+		}
+	}
+	yticks := ticks(0, hi)
+	ymax := yticks[len(yticks)-1]
+	y := func(v float64) float64 { return y1 - v/ymax*(y1-y0) }
+
+	// The x positions of the x values, and the labels of the x-axis.
+	pos := map[string]float64{}
+	type label struct {
+		x float64
+		s string
+	}
+	labels := []label{}
+	groupWidth := (x1 - x0) / float64(len(c.XS))
+	if c.numeric && c.Type == "line" {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, x := range c.XS {
+			v, _ := strconv.ParseFloat(x, 64)
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+		xticks := ticks(lo, hi)
+		lo, hi = xticks[0], xticks[len(xticks)-1]
+		for _, x := range c.XS {
+			v, _ := strconv.ParseFloat(x, 64)
+			pos[x] = x0 + (v-lo)/(hi-lo)*(x1-x0)
+		}
+		for _, t := range xticks {
+			labels = append(labels, label{x0 + (t-lo)/(hi-lo)*(x1-x0), formatTick(t)})
+		}
+	} else {
+		for i, x := range c.XS {
+			pos[x] = x0 + (float64(i)+0.5)*groupWidth
+			labels = append(labels, label{pos[x], x})
+		}
+	}
+
+	// Grid and axes.
+	for _, t := range yticks {
+		cv.line(x0, y(t), x1, y(t), "#dddddd", 1)
+		cv.text(x0-6, y(t)+fontSize/3, formatTick(t), "end", fontSize, false)
+	}
+	for _, l := range labels {
+		if c.Type == "line" {
+			cv.line(l.x, y0, l.x, y1, "#dddddd", 1)
+		}
+		cv.text(l.x, y1+fontSize+6, l.s, "middle", fontSize, false)
+	}
+	cv.line(x0, y1, x1, y1, "#333333", 1)
+	cv.line(x0, y0, x0, y1, "#333333", 1)
+	cv.text((x0+x1)/2, height-12, c.XLabel, "middle", fontSize, false)
+	cv.text(18, (y0+y1)/2, c.YLabel, "middle", fontSize, true)
+	cv.text(x0, top/2+fontSize/2, c.Title, "start", fontSize*1.5, false)
+
+	// Series.
+	errorBar := func(x float64, p Point, color string) {
+		cv.line(x, y(p.Lo), x, y(p.Hi), color, 1)
+		cv.line(x-3, y(p.Lo), x+3, y(p.Lo), color, 1)
+		cv.line(x-3, y(p.Hi), x+3, y(p.Hi), color, 1)
+	}
+	barWidth := groupWidth * 0.8 / float64(len(c.Series))
+	for i, s := range c.Series {
+		color := palette[i%len(palette)]
+		if c.Type == "line" {
+			pts := [][2]float64{}
+			xs := sortedXS(c, s)
+			for _, x := range xs {
+				pts = append(pts, [2]float64{pos[x], y(s.Points[x].Median)})
+			}
+			cv.polyline(pts, color, 2)
+			for _, x := range xs {
+				cv.circle(pos[x], y(s.Points[x].Median), 4, color)
+				errorBar(pos[x], s.Points[x], "#333333")
+			}
+		} else {
+			for _, x := range c.XS {
+				p, ok := s.Points[x]
+				if !ok {
+					continue
+				}
+				bx := pos[x] - groupWidth*0.4 + float64(i)*barWidth
+				cv.rect(bx, y(p.Median), barWidth, y1-y(p.Median), color)
+				errorBar(bx+barWidth/2, p, "#333333")
+			}
+		}
+
+		// Legend.
+		ly := y0 + float64(i)*(fontSize+10) + fontSize
+		cv.circle(x1+24, ly-fontSize/3, 5, color)
+		cv.text(x1+36, ly, s.Name, "start", fontSize, false)
+	}
+}
+
+// sortedXS returns the x values of the points of a series in the order
+// of the chart, i.e. numerically for numeric x values.
+func sortedXS(c *Chart, s *Series) []string {
+	xs := []string{}
+	for _, x := range c.XS {
+		if _, ok := s.Points[x]; ok {
+			xs = append(xs, x)
+		}
+	}
+	if c.numeric {
+		sort.SliceStable(xs, func(i, j int) bool {
+			a, _ := strconv.ParseFloat(xs[i], 64)
+			b, _ := strconv.ParseFloat(xs[j], 64)
+			return a < b
+		})
+	}
+	return xs
+}
+
+// ticks returns about five evenly spaced ticks of step 1, 2 or 5 times a
+// power of ten that cover the range from lo to hi.
+func ticks(lo, hi float64) []float64 {
+	if hi <= lo {
+		hi = lo + 1
+	}
+	step := math.Pow(10, math.Floor(math.Log10((hi-lo)/5)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if (hi-lo)/(step*m) <= 5 {
+			step *= m
+			break
+		}
+	}
+	ts := []float64{}
+	first := math.Floor(lo / step)
+	for i := 0.0; ; i++ {
+		t := (first + i) * step
+		ts = append(ts, t)
+		if t >= hi-step*1e-9 {
+			return ts
+		}
+	}
+}
+
+// formatTick formats a tick without trailing zeros and rounding errors.
+func formatTick(t float64) string {
+	return strconv.FormatFloat(t, 'g', 10, 64)
+}
+
+// svg is a canvas that produces an SVG image.
+type svg struct {
+	buf bytes.Buffer
+}
+
+func (s *svg) line(x1, y1, x2, y2 float64, color string, width float64) {
+	fmt.Fprintf(&s.buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="%g"/>`+"\n", x1, y1, x2, y2, color, width)
+}
+
+func (s *svg) polyline(pts [][2]float64, color string, width float64) {
+	coords := make([]string, len(pts))
+	for i, p := range pts {
+		coords[i] = fmt.Sprintf("%.1f,%.1f", p[0], p[1])
+	}
+	fmt.Fprintf(&s.buf, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%g"/>`+"\n", strings.Join(coords, " "), color, width)
+}
+
+func (s *svg) rect(x, y, w, h float64, color string) {
+	fmt.Fprintf(&s.buf, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`+"\n", x, y, w, h, color)
+}
+
+func (s *svg) circle(x, y, r float64, color string) {
+	fmt.Fprintf(&s.buf, `<circle cx="%.1f" cy="%.1f" r="%g" fill="%s"/>`+"\n", x, y, r, color)
+}
+
+func (s *svg) text(x, y float64, str, anchor string, size float64, rotate bool) {
+	if str == "" {
+		return
+	}
+	transform := ""
+	if rotate {
+		transform = fmt.Sprintf(` transform="rotate(-90 %.1f %.1f)"`, x, y)
+	}
+	fmt.Fprintf(&s.buf, `<text x="%.1f" y="%.1f" text-anchor="%s" font-size="%g"%s>%s</text>`+"\n", x, y, anchor, size, transform, html.EscapeString(str))
+}
+
+func (s *svg) bytes() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" font-family="sans-serif">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	b.Write(s.buf.Bytes())
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+// tikz is a canvas that produces a TikZ picture. A pixel is half a
+// point, such that a chart fits the text width of an article.
+type tikz struct {
+	body strings.Builder
+}
+
+// pt converts canvas coordinates to TikZ coordinates, whose origin is at
+// the bottom left.
+func (t *tikz) pt(x, y float64) string {
+	return fmt.Sprintf("(%.1f,%.1f)", x/2, (height-y)/2)
+}
+
+// color converts a hex color to the xcolor syntax.
+func (t *tikz) color(c string) string {
+	v, _ := strconv.ParseUint(strings.TrimPrefix(c, "#"), 16, 32)
+	return fmt.Sprintf("{rgb,255:red,%d;green,%d;blue,%d}", v>>16&0xff, v>>8&0xff, v&0xff)
+}
+
+func (t *tikz) line(x1, y1, x2, y2 float64, color string, width float64) {
+	fmt.Fprintf(&t.body, "\\draw[color=%s,line width=%gpt] %s -- %s;\n", t.color(color), width/2, t.pt(x1, y1), t.pt(x2, y2))
+}
+
+func (t *tikz) polyline(pts [][2]float64, color string, width float64) {
+	if len(pts) == 0 {
+		return
+	}
+	coords := make([]string, len(pts))
+	for i, p := range pts {
+		coords[i] = t.pt(p[0], p[1])
+	}
+	fmt.Fprintf(&t.body, "\\draw[color=%s,line width=%gpt] %s;\n", t.color(color), width/2, strings.Join(coords, " -- "))
+}
+
+func (t *tikz) rect(x, y, w, h float64, color string) {
+	fmt.Fprintf(&t.body, "\\fill[color=%s] %s rectangle %s;\n", t.color(color), t.pt(x, y+h), t.pt(x+w, y))
+}
+
+func (t *tikz) circle(x, y, r float64, color string) {
+	fmt.Fprintf(&t.body, "\\fill[color=%s] %s circle (%gpt);\n", t.color(color), t.pt(x, y), r/2)
+}
+
+var tikzEscape = strings.NewReplacer(
+	`\`, `\textbackslash{}`, `{`, `\{`, `}`, `\}`, `%`, `\%`, `#`, `\#`, `&`, `\&`,
+	`_`, `\_`, `$`, `\$`, `~`, `\textasciitilde{}`, `^`, `\textasciicircum{}`, `µ`, `$\mu$`,
+)
+
+func (t *tikz) text(x, y float64, s, anchor string, size float64, rotate bool) {
+	if s == "" {
+		return
+	}
+	// The canvas anchors text at its baseline.
+	a := map[string]string{"start": "base west", "middle": "base", "end": "base east"}[anchor]
+	font := `\scriptsize`
+	if size > fontSize {
+		font = `\normalsize`
+	}
+	opts := "anchor=" + a + ",inner sep=0pt"
+	if rotate {
+		opts += ",rotate=90"
+	}
+	fmt.Fprintf(&t.body, "\\node[%s] at %s {%s %s};\n", opts, t.pt(x, y), font, tikzEscape.Replace(s))
+}
+
+func (t *tikz) String() string {
+	return "\\begin{tikzpicture}[x=1pt,y=1pt]\n" + t.body.String() + "\\end{tikzpicture}\n"
+}
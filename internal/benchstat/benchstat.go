@@ -0,0 +1,533 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+// Package benchstat summarizes the benchmark results of the companion
+// code of golang.design research posts, which are recorded in the
+// standard Go benchmark format, i.e. the output of go test -bench:
+//
+//	goos: darwin
+//	goarch: arm64
+//	pkg: cgo-handle/cgo1
+//	BenchmarkHandle/non-concurrent-8    2822158    405.0 ns/op
+//
+// Similar to the benchstat command, the results of each benchmark are
+// summarized by their median and variation after removing outliers,
+// and compared to the results of a baseline file with the Mann-Whitney
+// U-test.
+//
+// A post embeds the summary of one or more files with the benchtable
+// shortcode, whose arguments are the paths of the files relative to
+// content/assets, optionally prefixed by a column label:
+//
+//	{{< benchtable "old=pointer-params/old.txt" "new=pointer-params/new.txt" >}}
+//
+// The first file is the baseline. As the Hugo templates cannot compute
+// the summary, research tables writes the tables of all shortcodes to
+// the Hugo data file data/benchtables.yaml, from which the shortcode
+// renders them. pdfgen renders the tables directly.
+package benchstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DataPath is the path of the tables relative to the site root.
+const DataPath = "data/benchtables.yaml"
+
+// AssetsDir is the directory relative to the site root that contains
+// the benchmark files.
+const AssetsDir = "content/assets"
+
+// A Result is a line of a benchmark file.
+type Result struct {
+	Config map[string]string // e.g. goos, goarch, pkg and cpu
+	Name   string            // the name without the Benchmark prefix
+	Iters  int
+	Values map[string]float64 // unit to value, e.g. ns/op: 405.0
+}
+
+// A File is a parsed benchmark file.
+type File struct {
+	Label   string
+	Path    string
+	Results []*Result
+}
+
+// Parse parses benchmark results in the standard format. Lines that are
+// neither configuration nor benchmark results, such as PASS, are ignored.
+func Parse(r io.Reader) ([]*Result, error) {
+	results := []*Result{}
+	config := map[string]string{}
+	shared := false // if config is referenced by a result
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if k, v, ok := configLine(line); ok {
+			if shared {
+				next := make(map[string]string, len(config))
+				for k, v := range config {
+					next[k] = v
+				}
+				config, shared = next, false
+			}
+			config[k] = v
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) < 4 || len(f)%2 != 0 || !strings.HasPrefix(f[0], "Benchmark") {
+			continue
+		}
+		iters, err := strconv.Atoi(f[1])
+		if err != nil {
+			continue
+		}
+		res := &Result{
+			Config: config,
+			Name:   strings.TrimPrefix(f[0], "Benchmark"),
+			Iters:  iters,
+			Values: map[string]float64{},
+		}
+		for i := 2; i < len(f); i += 2 {
+			v, err := strconv.ParseFloat(f[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value in %q: %v", line, err)
+			}
+			res.Values[f[i+1]] = v
+		}
+		shared = true
+		results = append(results, res)
+	}
+	return results, s.Err()
+}
+
+var reConfig = regexp.MustCompile(`^([a-z][^\s:]*):\s*(.*?)\s*$`)
+
+// configLine parses a configuration line, e.g. "goos: darwin".
+func configLine(line string) (key, value string, ok bool) {
+	m := reConfig.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// An Input is an argument of the benchtable shortcode.
+type Input struct {
+	Label string // the column label, defaults to the file name
+	Path  string // relative to content/assets
+}
+
+// ParseInput parses an argument of the form [label=]path.
+func ParseInput(arg string) Input {
+	if l, p, ok := strings.Cut(arg, "="); ok {
+		return Input{Label: l, Path: p}
+	}
+	return Input{Path: arg}
+}
+
+// A Shortcode is a use of the benchtable shortcode in a post.
+type Shortcode struct {
+	Text   string // the text of the shortcode
+	Key    string // the key of the tables in data/benchtables.yaml
+	Inputs []Input
+}
+
+var (
+	reShortcode = regexp.MustCompile(`\{\{<\s*benchtable\s+(.*?)\s*>\}\}`)
+	reArg       = regexp.MustCompile(`"([^"]*)"`)
+)
+
+// Shortcodes returns the benchtable shortcodes in a post.
+func Shortcodes(b []byte) []Shortcode {
+	scs := []Shortcode{}
+	for _, m := range reShortcode.FindAllSubmatch(b, -1) {
+		sc := Shortcode{Text: string(m[0])}
+		args := []string{}
+		for _, a := range reArg.FindAllSubmatch(m[1], -1) {
+			args = append(args, string(a[1]))
+			sc.Inputs = append(sc.Inputs, ParseInput(string(a[1])))
+		}
+		// The key must match the key that the shortcode computes from
+		// its arguments.
+		sc.Key = strings.Join(args, " ")
+		scs = append(scs, sc)
+	}
+	return scs
+}
+
+// Load loads the inputs of a shortcode in the site at root.
+func Load(root string, inputs []Input) ([]*File, error) {
+	files := make([]*File, 0, len(inputs))
+	for _, in := range inputs {
+		f, err := os.Open(filepath.Join(root, AssetsDir, filepath.FromSlash(in.Path)))
+		if err != nil {
+			return nil, err
+		}
+		results, err := Parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", in.Path, err)
+		}
+		if len(results) == 0 {
+			return nil, fmt.Errorf("%v: no benchmark results", in.Path)
+		}
+		files = append(files, &File{Label: in.Label, Path: in.Path, Results: results})
+	}
+	labelFiles(files)
+	return files, nil
+}
+
+// labelFiles labels the files without a label by the shortest suffix
+// of their paths, without extension, that distinguishes them.
+func labelFiles(files []*File) {
+	for n := 1; ; n++ {
+		seen := map[string]bool{}
+		unique, done := true, true
+		labels := make([]string, len(files))
+		for i, f := range files {
+			if f.Label != "" {
+				continue
+			}
+			parts := strings.Split(strings.TrimSuffix(f.Path, filepath.Ext(f.Path)), "/")
+			if n < len(parts) {
+				done = false
+				parts = parts[len(parts)-n:]
+			}
+			labels[i] = strings.Join(parts, "/")
+			unique = unique && !seen[labels[i]]
+			seen[labels[i]] = true
+		}
+		if unique || done {
+			for i, f := range files {
+				if f.Label == "" {
+					f.Label = labels[i]
+				}
+			}
+			return
+		}
+	}
+}
+
+// A Table is the summary of the results of a unit.
+type Table struct {
+	Caption string     `yaml:"caption"`
+	Header  []string   `yaml:"header"`
+	Rows    [][]string `yaml:"rows"`
+}
+
+// A Stat is the summary of the results of a benchmark.
+type Stat struct {
+	Values    []float64 // the values without outliers
+	Median    float64
+	Variation float64 // the maximum deviation from the median relative to it
+}
+
+// Summarize summarizes the given values.
+func Summarize(values []float64) Stat {
+	v := append([]float64{}, values...)
+	sort.Float64s(v)
+	q1, q3 := quantile(v, 0.25), quantile(v, 0.75)
+	lo, hi := q1-1.5*(q3-q1), q3+1.5*(q3-q1)
+	s := Stat{}
+	for _, x := range v {
+		if lo <= x && x <= hi {
+			s.Values = append(s.Values, x)
+		}
+	}
+	s.Median = quantile(s.Values, 0.5)
+	for _, x := range s.Values {
+		if s.Median != 0 {
+			s.Variation = math.Max(s.Variation, math.Abs(x-s.Median)/s.Median)
+		}
+	}
+	return s
+}
+
+// quantile returns the q-quantile of the sorted values.
+func quantile(v []float64, q float64) float64 {
+	if len(v) == 0 {
+		return math.NaN()
+	}
+	pos := q * float64(len(v)-1)
+	i := int(pos)
+	if i+1 >= len(v) {
+		return v[len(v)-1]
+	}
+	return v[i] + (pos-float64(i))*(v[i+1]-v[i])
+}
+
+// MannWhitney returns the two-sided p-value of the Mann-Whitney U-test of
+// the samples x and y, using the normal approximation with tie correction.
+func MannWhitney(x, y []float64) float64 {
+	type sample struct {
+		v float64
+		x bool
+	}
+	all := make([]sample, 0, len(x)+len(y))
+	for _, v := range x {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range y {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	n1, n2, n := float64(len(x)), float64(len(y)), float64(len(all))
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	rx, ties := 0.0, 0.0
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // the average of the ranks i+1..j
+		for k := i; k < j; k++ {
+			if all[k].x {
+				rx += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	u := rx - n1*(n1+1)/2
+	mu := n1 * n2 / 2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := math.Max(math.Abs(u-mu)-0.5, 0) / sigma
+	return math.Erfc(z / math.Sqrt2)
+}
+
+// Tables summarizes the files, one table per unit. The first file is
+// the baseline of the deltas.
+func Tables(files []*File) []*Table {
+	// Collect the values by unit and benchmark, in the order of their
+	// first appearance.
+	units, names := []string{}, map[string][]string{}
+	values := make([]map[string]map[string][]float64, len(files)) // file, unit, name
+	for i, f := range files {
+		values[i] = map[string]map[string][]float64{}
+		multi := len(configValues(f, "pkg")) > 1
+		for _, r := range f.Results {
+			name := r.Name
+			if multi {
+				name = r.Config["pkg"] + "." + name
+			}
+			for _, unit := range sortedUnits(r.Values) {
+				if values[i][unit] == nil {
+					values[i][unit] = map[string][]float64{}
+				}
+				if _, ok := names[unit]; !ok {
+					units = append(units, unit)
+				}
+				if !contains(names[unit], name) {
+					names[unit] = append(names[unit], name)
+				}
+				values[i][unit][name] = append(values[i][unit][name], r.Values[unit])
+			}
+		}
+	}
+
+	caption := caption(files)
+	tables := []*Table{}
+	for _, unit := range units {
+		t := &Table{Caption: caption, Header: []string{"name"}}
+		for i, f := range files {
+			t.Header = append(t.Header, f.Label+" "+Quantity(unit))
+			if i > 0 {
+				t.Header = append(t.Header, "delta")
+			}
+		}
+		for _, name := range names[unit] {
+			row := []string{name}
+			base := Summarize(values[0][unit][name])
+			for i := range files {
+				v := values[i][unit][name]
+				if len(v) == 0 {
+					row = append(row, "")
+					if i > 0 {
+						row = append(row, "")
+					}
+					continue
+				}
+				s := Summarize(v)
+				row = append(row, fmt.Sprintf("%s ± %.0f%%", format(s.Median, unit), s.Variation*100))
+				if i > 0 {
+					row = append(row, delta(base, s))
+				}
+			}
+			t.Rows = append(t.Rows, row)
+		}
+		tables = append(tables, t)
+	}
+	return tables
+}
+
+// delta formats the change from the baseline to s. Changes that are not
+// statistically significant are shown as ~.
+func delta(base, s Stat) string {
+	if len(base.Values) == 0 {
+		return ""
+	}
+	p := MannWhitney(base.Values, s.Values)
+	d := "~"
+	if p < 0.05 && base.Median != 0 {
+		d = fmt.Sprintf("%+.2f%%", (s.Median/base.Median-1)*100)
+	}
+	return fmt.Sprintf("%s (p=%.3f n=%d+%d)", d, p, len(base.Values), len(s.Values))
+}
+
+// caption describes the configuration of the files. A configuration
+// that differs between files is listed per file.
+func caption(files []*File) string {
+	keys := []string{}
+	for _, f := range files {
+		for _, r := range f.Results {
+			for k := range r.Config {
+				if !contains(keys, k) {
+					keys = append(keys, k)
+				}
+			}
+		}
+	}
+	sortByOrder(keys, "goos", "goarch", "pkg", "cpu")
+
+	parts := []string{}
+	for _, k := range keys {
+		all := []string{}
+		per := []string{}
+		for _, f := range files {
+			vs := configValues(f, k)
+			per = append(per, fmt.Sprintf("%s (%s)", strings.Join(vs, ", "), f.Label))
+			for _, v := range vs {
+				if !contains(all, v) {
+					all = append(all, v)
+				}
+			}
+		}
+		if len(all) == 1 {
+			parts = append(parts, k+": "+all[0])
+		} else {
+			parts = append(parts, k+": "+strings.Join(per, "; "))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// configValues returns the distinct values of a configuration key in
+// a file.
+func configValues(f *File, key string) []string {
+	vs := []string{}
+	for _, r := range f.Results {
+		if v, ok := r.Config[key]; ok && !contains(vs, v) {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// sortedUnits returns the units of the values in the order of the go
+// test output, i.e. ns/op first.
+func sortedUnits(values map[string]float64) []string {
+	units := make([]string, 0, len(values))
+	for u := range values {
+		units = append(units, u)
+	}
+	sortByOrder(units, "ns/op", "MB/s", "B/op", "allocs/op")
+	return units
+}
+
+// sortByOrder sorts s such that the given strings come first in the
+// given order, followed by the other strings in lexical order.
+func sortByOrder(s []string, first ...string) {
+	rank := func(x string) int {
+		for i, f := range first {
+			if f == x {
+				return i
+			}
+		}
+		return len(first)
+	}
+	sort.Slice(s, func(i, j int) bool {
+		ri, rj := rank(s[i]), rank(s[j])
+		return ri < rj || ri == rj && s[i] < s[j]
+	})
+}
+
+// Quantity names the measured quantity of a unit as benchstat does.
+func Quantity(unit string) string {
+	switch unit {
+	case "ns/op":
+		return "time/op"
+	case "MB/s":
+		return "speed"
+	case "B/op":
+		return "alloc/op"
+	}
+	return unit
+}
+
+// A scale is a multiple of a unit, e.g. µs of ns/op.
+type scale struct {
+	factor float64
+	suffix string
+}
+
+// scales are the multiples of the units with a well-known magnitude.
+var scales = map[string][]scale{
+	"ns/op": {{1, "ns"}, {1e3, "µs"}, {1e6, "ms"}, {1e9, "s"}},
+	"B/op":  {{1, "B"}, {1e3, "kB"}, {1e6, "MB"}, {1e9, "GB"}},
+	"MB/s":  {{1, "MB/s"}, {1e3, "GB/s"}},
+}
+
+// Scale returns the multiple of a unit that is suitable for a value,
+// e.g. 1e3 and µs for 1234.5 ns/op. Units without well-known multiples
+// are not scaled and have no suffix.
+func Scale(v float64, unit string) (factor float64, suffix string) {
+	sc := scale{1, ""}
+	for _, x := range scales[unit] {
+		if x.factor == 1 || math.Abs(v) >= x.factor {
+			sc = x
+		}
+	}
+	return sc.factor, sc.suffix
+}
+
+// format formats a value of a unit with three significant digits and a
+// scaled unit, e.g. 1234.5 ns/op as 1.23µs.
+func format(v float64, unit string) string {
+	factor, suffix := Scale(v, unit)
+	v /= factor
+	switch a := math.Abs(v); {
+	case a == 0 || a >= 100:
+		return fmt.Sprintf("%.0f%s", v, suffix)
+	case a >= 10:
+		return fmt.Sprintf("%.1f%s", v, suffix)
+	default:
+		return fmt.Sprintf("%.2f%s", v, suffix)
+	}
+}
+
+func contains(s []string, x string) bool {
+	for _, v := range s {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package benchstat
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const oldTxt = `goos: linux
+goarch: amd64
+pkg: pparam
+cpu: Intel(R) Core(TM) i9-9900K CPU @ 3.60GHz
+BenchmarkVec
+BenchmarkVec/addv
+BenchmarkVec/addv-16    244740026    5.00 ns/op    0 B/op    0 allocs/op
+BenchmarkVec/addv-16    238191192    4.90 ns/op    0 B/op    0 allocs/op
+BenchmarkVec/addv-16    240000000    4.95 ns/op    0 B/op    0 allocs/op
+BenchmarkVec/addv-16    240000000    5.05 ns/op    0 B/op    0 allocs/op
+BenchmarkVec/addv-16    240000000    9.00 ns/op    0 B/op    0 allocs/op
+PASS
+ok  	pparam	12.345s
+`
+
+const newTxt = `goos: linux
+goarch: amd64
+pkg: pparam
+cpu: Intel(R) Core(TM) i9-9900K CPU @ 3.60GHz
+BenchmarkVec/addv-16    1000000000    0.24 ns/op    0 B/op    0 allocs/op
+BenchmarkVec/addv-16    1000000000    0.25 ns/op    0 B/op    0 allocs/op
+BenchmarkVec/addv-16    1000000000    0.26 ns/op    0 B/op    0 allocs/op
+BenchmarkVec/addv-16    1000000000    0.27 ns/op    0 B/op    0 allocs/op
+BenchmarkVec/addv-16    1000000000    0.25 ns/op    0 B/op    0 allocs/op
+`
+
+func TestParse(t *testing.T) {
+	results, err := Parse(strings.NewReader(oldTxt))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("Parse: got %d results, want 5", len(results))
+	}
+	r := results[0]
+	if r.Name != "Vec/addv-16" || r.Iters != 244740026 || r.Config["pkg"] != "pparam" || r.Config["goos"] != "linux" {
+		t.Fatalf("Parse: got %+v", r)
+	}
+	want := map[string]float64{"ns/op": 5.00, "B/op": 0, "allocs/op": 0}
+	if !reflect.DeepEqual(r.Values, want) {
+		t.Fatalf("Parse: got values %v, want %v", r.Values, want)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{5.00, 4.90, 4.95, 5.05, 9.00})
+	if len(s.Values) != 4 {
+		t.Fatalf("Summarize: got %v, want the outlier removed", s.Values)
+	}
+	if math.Abs(s.Median-4.975) > 1e-9 {
+		t.Fatalf("Summarize: got median %v, want 4.975", s.Median)
+	}
+	if math.Abs(s.Variation-0.075/4.975) > 1e-9 {
+		t.Fatalf("Summarize: got variation %v", s.Variation)
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	y := []float64{11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+	if p := MannWhitney(x, y); p > 0.001 {
+		t.Fatalf("MannWhitney: got p=%v for disjoint samples", p)
+	}
+	if p := MannWhitney(x, x); p != 1 {
+		t.Fatalf("MannWhitney: got p=%v for equal samples", p)
+	}
+	if p := MannWhitney([]float64{0, 0}, []float64{0, 0}); p != 1 {
+		t.Fatalf("MannWhitney: got p=%v for constant samples", p)
+	}
+}
+
+func TestShortcodes(t *testing.T) {
+	post := "Before.\n\n" +
+		`{{< benchtable "old=pointer-params/old.txt" "pointer-params/new.txt" >}}` +
+		"\n\nAfter.\n"
+	got := Shortcodes([]byte(post))
+	want := []Shortcode{{
+		Text: `{{< benchtable "old=pointer-params/old.txt" "pointer-params/new.txt" >}}`,
+		Key:  "old=pointer-params/old.txt pointer-params/new.txt",
+		Inputs: []Input{
+			{Label: "old", Path: "pointer-params/old.txt"},
+			{Path: "pointer-params/new.txt"},
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Shortcodes: got %+v, want %+v", got, want)
+	}
+}
+
+func TestTables(t *testing.T) {
+	oldResults, _ := Parse(strings.NewReader(oldTxt))
+	newResults, _ := Parse(strings.NewReader(newTxt))
+	files := []*File{
+		{Path: "pointer-params/old.txt", Results: oldResults},
+		{Path: "pointer-params/new.txt", Results: newResults},
+	}
+	labelFiles(files)
+
+	tables := Tables(files)
+	if len(tables) != 3 {
+		t.Fatalf("Tables: got %d tables, want 3", len(tables))
+	}
+	want := &Table{
+		Caption: "goos: linux, goarch: amd64, pkg: pparam, cpu: Intel(R) Core(TM) i9-9900K CPU @ 3.60GHz",
+		Header:  []string{"name", "old time/op", "new time/op", "delta"},
+		Rows: [][]string{
+			{"Vec/addv-16", "4.97ns ± 2%", "0.25ns ± 8%", "-94.97% (p=0.019 n=4+5)"},
+		},
+	}
+	if !reflect.DeepEqual(tables[0], want) {
+		t.Fatalf("Tables: got %+v, want %+v", tables[0], want)
+	}
+	if got := tables[2].Rows[0][3]; got != "~ (p=1.000 n=5+5)" {
+		t.Fatalf("Tables: got delta %q for equal allocations", got)
+	}
+}
+
+func TestLabelFiles(t *testing.T) {
+	files := []*File{
+		{Path: "cgo-handle/cgo1/bench.txt"},
+		{Path: "cgo-handle/cgo2/bench.txt"},
+		{Label: "base", Path: "bench.txt"},
+	}
+	labelFiles(files)
+	got := []string{files[0].Label, files[1].Label, files[2].Label}
+	want := []string{"cgo1/bench", "cgo2/bench", "base"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("labelFiles: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+// Package bib implements the site-level bibliography of the golang.design
+// research posts. The bibliography is the Hugo data file
+// data/bibliography.yaml, which maps citation keys to references in the
+// same format as the references of a post:
+//
+//	ou2020bench: Changkun Ou. 2020. Conduct Reliable Benchmarking in Go. TalkGo Meetup. Virtual Event. March 26. https://golang.design/s/gobench
+//
+// A post cites a reference of the bibliography by its key, e.g.
+// [^ou2020bench], without defining it. Instead, its references contain
+// the shortcode
+//
+//	{{% bibliography %}}
+//
+// which renders the cited references of the bibliography.
+package bib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.design/x/research/internal/post"
+	"golang.design/x/research/internal/site"
+	"gopkg.in/yaml.v3"
+)
+
+// Path is the path of the bibliography relative to the site root.
+const Path = "data/bibliography.yaml"
+
+// A Bibliography maps citation keys to references.
+type Bibliography map[string]string
+
+// Load loads the bibliography of the site that contains dir. It returns
+// an empty bibliography if the site does not have one.
+func Load(dir string) (Bibliography, error) {
+	root, err := site.Root(dir)
+	if err != nil {
+		return Bibliography{}, nil
+	}
+	b, err := os.ReadFile(filepath.Join(root, Path))
+	if os.IsNotExist(err) {
+		return Bibliography{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	bib := Bibliography{}
+	if err := yaml.Unmarshal(b, &bib); err != nil {
+		return nil, fmt.Errorf("%v: %w", Path, err)
+	}
+	for k, v := range bib {
+		bib[k] = strings.TrimSpace(v)
+	}
+	return bib, nil
+}
+
+// Resolve returns the references of a post: all references defined by
+// the post, followed by the cited references that are only defined in
+// the bibliography, in the order of their first citation. It returns
+// an error if a citation is defined by neither of them.
+func (bib Bibliography) Resolve(refs []post.Reference, citations []string) ([]post.Reference, error) {
+	defined := map[string]bool{}
+	for _, ref := range refs {
+		defined[ref.Key] = true
+	}
+
+	resolved := append([]post.Reference{}, refs...)
+	undefined := []string{}
+	for _, key := range citations {
+		if defined[key] {
+			continue
+		}
+		text, ok := bib[key]
+		if !ok {
+			undefined = append(undefined, "[^"+key+"]")
+			continue
+		}
+		resolved = append(resolved, post.Reference{Key: key, Text: text})
+		defined[key] = true
+	}
+	if len(undefined) > 0 {
+		return nil, fmt.Errorf("undefined references %v", strings.Join(undefined, ", "))
+	}
+	return resolved, nil
+}
+
+// A Definition is a definition of a reference in a file.
+type Definition struct {
+	File string
+	Text string
+}
+
+// A Conflict is a citation key that has different definitions.
+type Conflict struct {
+	Key         string
+	Definitions []Definition
+}
+
+func (c Conflict) String() string {
+	var w strings.Builder
+	fmt.Fprintf(&w, "conflicting definitions of [^%v]:", c.Key)
+	for _, d := range c.Definitions {
+		fmt.Fprintf(&w, "\n\t%v: %v", d.File, d.Text)
+	}
+	return w.String()
+}
+
+// Conflicts returns the keys that are defined differently in the
+// bibliography and the given references of posts, which are indexed
+// by the file name of the post. Definitions are considered equal if
+// they only differ in whitespace.
+func (bib Bibliography) Conflicts(posts map[string][]post.Reference) []Conflict {
+	defs := map[string][]Definition{}
+	for key, text := range bib {
+		defs[key] = append(defs[key], Definition{Path, text})
+	}
+	files := make([]string, 0, len(posts))
+	for file := range posts {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		for _, ref := range posts[file] {
+			defs[ref.Key] = append(defs[ref.Key], Definition{file, ref.Text})
+		}
+	}
+
+	conflicts := []Conflict{}
+	for key, ds := range defs {
+		for _, d := range ds[1:] {
+			if normalize(d.Text) != normalize(ds[0].Text) {
+				conflicts = append(conflicts, Conflict{key, ds})
+				break
+			}
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Key < conflicts[j].Key
+	})
+	return conflicts
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
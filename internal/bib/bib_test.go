@@ -0,0 +1,61 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package bib
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.design/x/research/internal/post"
+)
+
+func TestResolve(t *testing.T) {
+	bibl := Bibliography{
+		"shared":  "A shared reference.",
+		"unused":  "An unused reference.",
+		"defined": "A reference that is overridden by the post.",
+	}
+	refs := []post.Reference{{Key: "defined", Text: "A local reference."}}
+
+	got, err := bibl.Resolve(refs, []string{"shared", "defined"})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	want := []post.Reference{
+		{Key: "defined", Text: "A local reference."},
+		{Key: "shared", Text: "A shared reference."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Resolve: got %v, want %v", got, want)
+	}
+
+	if _, err := bibl.Resolve(refs, []string{"missing"}); err == nil {
+		t.Fatalf("Resolve: expect an error for undefined references")
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	bibl := Bibliography{"go2021spec": "The Go Authors. The Go Programming Language Specification."}
+	posts := map[string][]post.Reference{
+		"a.md": {
+			{Key: "go2021spec", Text: "The Go Authors.\nThe Go Programming Language Specification."},
+			{Key: "taylor2021typeparam", Text: "Ian Lance Taylor. Type Parameters. March 19, 2021."},
+		},
+		"b.md": {
+			{Key: "taylor2021typeparam", Text: "Ian Lance Taylor. Type Parameters. 2021."},
+		},
+	}
+
+	got := bibl.Conflicts(posts)
+	want := []Conflict{{
+		Key: "taylor2021typeparam",
+		Definitions: []Definition{
+			{"a.md", "Ian Lance Taylor. Type Parameters. March 19, 2021."},
+			{"b.md", "Ian Lance Taylor. Type Parameters. 2021."},
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Conflicts: got %v, want %v", got, want)
+	}
+}
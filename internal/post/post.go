@@ -0,0 +1,433 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+// Package post parses the markdown files of the golang.design research
+// posts. Besides the front matter, a post follows the conventions:
+//
+//	Author(s): [FirstName LastName](mailto:email), [FirstName LastName](mailto:email)
+//
+//	Permalink: https://golang.design/research/slug
+//
+//	<!--abstract-->
+//	abstract content goes here...
+//	<!--more-->
+//
+//	content body...
+//
+//	## References
+//
+//	[^ou2020bench]: Changkun Ou. 2020. Conduct Reliable Benchmarking in Go. TalkGo Meetup. Virtual Event. March 26. https://golang.design/s/gobench
+package post
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"mvdan.cc/xurls/v2"
+)
+
+// Markdown is the markdown parser of posts.
+var Markdown = goldmark.New(
+	goldmark.WithExtensions(
+		meta.Meta,
+	),
+)
+
+// Meta returns the front matter of the given post.
+func Meta(b []byte) (map[string]any, error) {
+	var buf bytes.Buffer
+	context := parser.NewContext()
+	if err := Markdown.Convert(b, &buf, parser.WithContext(context)); err != nil {
+		return nil, err
+	}
+	return meta.TryGet(context)
+}
+
+// Date returns the date in the given front matter.
+func Date(metaData map[string]any) (time.Time, error) {
+	dateRaw, ok := metaData["date"]
+	if !ok {
+		return time.Time{}, errors.New("metadata missing date information")
+	}
+	date, ok := dateRaw.(string)
+	if !ok {
+		return time.Time{}, errors.New("metadata contains invalid date format")
+	}
+	t, err := time.Parse("2006-01-02T15:04:05Z07:00", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse date: %w", err)
+	}
+	return t, nil
+}
+
+// Slug returns the slug in the front matter of the post at path without
+// slashes. Like Hugo, a post without a slug is named after its file.
+func Slug(path string, metaData map[string]any) string {
+	slug, _ := metaData["slug"].(string)
+	if slug = strings.Trim(slug, "/"); slug != "" {
+		return slug
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// An Author is an author of a post.
+type Author struct {
+	Name  string
+	Email string
+}
+
+// ErrAuthors is returned if a post does not list its authors.
+var ErrAuthors = errors.New(`cannot find authors, make sure the markdown uses the correct convention:
+
+Author(s): [FirstName LastName](mailto:email), [FirstName LastName](mailto:email)`)
+
+// Authors returns the authors of the given post. The obfuscated emails
+// of the authors are converted to their actual addresses.
+func Authors(b []byte) ([]Author, error) {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	authors := []Author{}
+
+	for s.Scan() {
+		l := s.Text()
+		if strings.HasPrefix(l, "Author(s): ") {
+			authorsStr := strings.TrimPrefix(l, "Author(s): ")
+			authorList := strings.Split(authorsStr, ", ")
+
+			for _, a := range authorList {
+				before, after, ok := strings.Cut(a, "](")
+				if !ok {
+					continue
+				}
+				name := strings.TrimPrefix(before, "[")
+				email := strings.TrimPrefix(strings.TrimSuffix(after, ")"), "mailto:")
+				email = strings.ReplaceAll(email, "[at]", "@")
+				authors = append(authors, Author{name, email})
+			}
+		}
+	}
+
+	if len(authors) == 0 {
+		return nil, ErrAuthors
+	}
+	return authors, nil
+}
+
+// Permalink returns the URL of the Permalink: line of the given post,
+// or an empty string if there is none.
+func Permalink(b []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		l := s.Text()
+		if strings.HasPrefix(l, "Permalink: ") {
+			return xurls.Strict().FindString(l)
+		}
+	}
+	return ""
+}
+
+// ErrAbstract is returned if a post does not have an abstract.
+var ErrAbstract = errors.New(`cannot find abstract, make sure the markdown uses the correct convention:
+
+	<!--abstract-->
+	abstract content goes here...
+	<!--more-->
+	`)
+
+// Abstract returns the abstract of the given post.
+func Abstract(b []byte) (string, error) {
+	_, content, ok := strings.Cut(string(b), "<!--abstract-->\n")
+	if !ok {
+		return "", ErrAbstract
+	}
+	content, _, ok = strings.Cut(content, "\n<!--more-->")
+	if !ok {
+		return "", ErrAbstract
+	}
+	return content, nil
+}
+
+// ErrBody is returned if a post does not have a body.
+var ErrBody = errors.New(`cannot find body, make sure the markdown uses the correct convention:
+
+	<!--more-->
+
+	content body...
+
+	## References
+	`)
+
+// Body returns the body of the given post, which is the content
+// between the abstract and the references.
+func Body(b []byte) (string, error) {
+	_, content, ok := strings.Cut(string(b), "\n<!--more-->")
+	if !ok {
+		return "", ErrBody
+	}
+	content, _, ok = strings.Cut(content, "## References")
+	if !ok {
+		return "", ErrBody
+	}
+	return content, nil
+}
+
+// ErrReferences is returned if a post does not have references.
+var ErrReferences = errors.New(`cannot find references, make sure the markdown uses the correct convention:
+
+		## References
+
+		[^ou2022bench]: Changkun Ou. 2020. Conduct Reliable Benchmarking in Go. TalkGo Meetup. Virtual Event. March 26. https://golang.design/s/gobench
+		`)
+
+// A Reference is an entry of the references of a post.
+type Reference struct {
+	Key  string
+	Text string // may span multiple lines
+}
+
+// References returns the references of the given post in the order
+// of their definitions. Hugo shortcodes in the references, such as the
+// {{% bibliography %}} of the site-level bibliography, are skipped.
+func References(b []byte) ([]Reference, error) {
+	_, content, ok := strings.Cut(string(b), "## References\n")
+	if !ok {
+		return nil, ErrReferences
+	}
+
+	refs := []Reference{}
+	s := bufio.NewScanner(strings.NewReader(content))
+	for s.Scan() {
+		l := s.Text()
+		if m := reDefinition.FindStringSubmatch(l); m != nil {
+			refs = append(refs, Reference{Key: m[1], Text: strings.TrimSpace(l[len(m[0]):])})
+			continue
+		}
+		if strings.TrimSpace(l) == "" || strings.HasPrefix(l, "{{") || len(refs) == 0 {
+			continue
+		}
+		refs[len(refs)-1].Text += "\n" + l
+	}
+	return refs, nil
+}
+
+var (
+	// https://stackoverflow.com/questions/1919982/regex-smallest-possible-match-or-nongreedy-match
+	reCitation   = regexp.MustCompile(`\[\^(.*?)\]`)
+	reDefinition = regexp.MustCompile(`^\[\^(.*?)\]:`)
+)
+
+// ReplaceCitations replaces all citations [^key] in s by repl, where
+// $1 in repl denotes the citation key.
+func ReplaceCitations(s, repl string) string {
+	return reCitation.ReplaceAllString(s, repl)
+}
+
+var reLink = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+
+// PlainText strips the markdown syntax that is commonly used in an
+// abstract, i.e. links, citations and inline code.
+func PlainText(s string) string {
+	s = ReplaceCitations(s, "")
+	s = reLink.ReplaceAllString(s, "$1")
+	s = strings.ReplaceAll(s, "`", "")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Citations returns the keys of all citations in the given post in the
+// order of their first occurrence. Citations inside of code, as well as
+// the definitions of the references, are not considered.
+func Citations(b []byte) []string {
+	code := codeSegments(b)
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, m := range reCitation.FindAllSubmatchIndex(b, -1) {
+		if overlaps(code, m[0], m[1]) {
+			continue
+		}
+		// Skip the definition of a reference.
+		if m[1] < len(b) && b[m[1]] == ':' && (m[0] == 0 || b[m[0]-1] == '\n') {
+			continue
+		}
+		key := string(b[m[2]:m[3]])
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// A Link is a link to another post, which is written as [[slug]] or
+// [text](research:slug).
+type Link struct {
+	Slug  string
+	Text  string // the text of the link, or empty for [[slug]]
+	Start int    // the byte offset of the link
+	Stop  int    // the byte offset after the link
+}
+
+var (
+	reWikiLink = regexp.MustCompile(`\[\[([a-z0-9-]+)\]\]`)
+	rePostLink = regexp.MustCompile(`\[([^\]]*)\]\(research:([a-z0-9-]+)\)`)
+)
+
+// Links returns the links to other posts in the given post in the order
+// of their occurrence. Links inside of code are not considered.
+func Links(b []byte) []Link {
+	code := codeSegments(b)
+	links := []Link{}
+	for _, m := range reWikiLink.FindAllSubmatchIndex(b, -1) {
+		if !overlaps(code, m[0], m[1]) {
+			links = append(links, Link{Slug: string(b[m[2]:m[3]]), Start: m[0], Stop: m[1]})
+		}
+	}
+	for _, m := range rePostLink.FindAllSubmatchIndex(b, -1) {
+		if !overlaps(code, m[0], m[1]) {
+			links = append(links, Link{Slug: string(b[m[4]:m[5]]), Text: string(b[m[2]:m[3]]), Start: m[0], Stop: m[1]})
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Start < links[j].Start })
+	return links
+}
+
+// An Image is a markdown image of a post, written as ![alt](src) or
+// ![alt](src "title").
+type Image struct {
+	Alt   string // the alternative text
+	Src   string
+	Title string // the title, which is the caption of the figure
+	Start int    // the byte offset of the image
+	Stop  int    // the byte offset after the image
+}
+
+var reImage = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"([^"]*)")?\)`)
+
+// Images returns the images in the given post in the order of their
+// occurrence. Images inside of code are not considered.
+func Images(b []byte) []Image {
+	code := codeSegments(b)
+	images := []Image{}
+	for _, m := range reImage.FindAllSubmatchIndex(b, -1) {
+		if overlaps(code, m[0], m[1]) {
+			continue
+		}
+		img := Image{Alt: string(b[m[2]:m[3]]), Src: string(b[m[4]:m[5]]), Start: m[0], Stop: m[1]}
+		if m[6] >= 0 {
+			img.Title = string(b[m[6]:m[7]])
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+// Standalone reports whether the image is the only content of its line
+// in b, which makes it a figure rather than an inline image.
+func (img Image) Standalone(b []byte) bool {
+	if img.Start > 0 && b[img.Start-1] != '\n' {
+		return false
+	}
+	rest := b[img.Stop:lineEnd(b, img.Stop)]
+	return len(bytes.TrimRight(rest, " \t\r\n")) == 0
+}
+
+// A CodeBlock is a fenced code block of a post.
+type CodeBlock struct {
+	Info  string // the info string after the opening fence, e.g. "go"
+	Open  int    // the byte offset of the opening fence
+	Start int    // the byte offset of the first line of code
+	Stop  int    // the byte offset after the last line of code
+	Close int    // the byte offset after the closing fence
+}
+
+// CodeBlocks returns the fenced code blocks of the given post. Empty
+// code blocks are only returned if they have an info string.
+func CodeBlocks(b []byte) []CodeBlock {
+	blocks := []CodeBlock{}
+	doc := Markdown.Parser().Parse(text.NewReader(b))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		fcb, ok := n.(*ast.FencedCodeBlock)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		block := CodeBlock{}
+		if fcb.Info != nil {
+			block.Info = string(fcb.Info.Segment.Value(b))
+		}
+		lines := fcb.Lines()
+		switch {
+		case lines.Len() > 0:
+			block.Start = lines.At(0).Start
+			block.Stop = lines.At(lines.Len() - 1).Stop
+		case fcb.Info != nil:
+			block.Start = lineEnd(b, fcb.Info.Segment.Stop)
+			block.Stop = block.Start
+		default:
+			return ast.WalkSkipChildren, nil
+		}
+		block.Open = bytes.LastIndexByte(b[:block.Start-1], '\n') + 1
+		block.Close = lineEnd(b, block.Stop)
+		blocks = append(blocks, block)
+		return ast.WalkSkipChildren, nil
+	})
+	return blocks
+}
+
+// lineEnd returns the byte offset after the end of the line at i.
+func lineEnd(b []byte, i int) int {
+	j := bytes.IndexByte(b[i:], '\n')
+	if j < 0 {
+		return len(b)
+	}
+	return i + j + 1
+}
+
+// overlaps reports whether any of the segments overlaps the range from
+// start to stop.
+func overlaps(segs []text.Segment, start, stop int) bool {
+	for _, seg := range segs {
+		if start < seg.Stop && seg.Start < stop {
+			return true
+		}
+	}
+	return false
+}
+
+// codeSegments returns the segments of all code blocks and code spans
+// of the given post.
+func codeSegments(b []byte) []text.Segment {
+	segs := []text.Segment{}
+	doc := Markdown.Parser().Parse(text.NewReader(b))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				segs = append(segs, lines.At(i))
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeSpan:
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					segs = append(segs, t.Segment)
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return segs
+}
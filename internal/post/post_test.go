@@ -0,0 +1,186 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package post
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testPost = "---" + `
+date: 2020-09-30T09:02:20+01:00
+slug: /bench-time
+tags:
+  - Benchmark
+title: Eliminating A Source of Measurement Errors in Benchmarks
+---
+
+Author(s): [Changkun Ou](mailto:research[at]changkun.de), [Jane Doe](mailto:jane@example.com)
+
+Permalink: https://golang.design/research/bench-time
+
+<!--abstract-->
+About six months ago, I did a presentation[^ou2020bench].
+<!--more-->
+
+## Introduction
+
+See the issue[^ou2020timer] and again the presentation[^ou2020bench].
+
+` + "```go" + `
+x := a[^b]
+` + "```" + `
+
+And ` + "`a[^c]`" + ` is not a citation either.
+
+## References
+
+[^ou2020timer]: Changkun Ou. 2020. testing: inconsistent benchmark measurements when interrupts timer.
+The Go Project Issue Tracker. Sep 26. https://go.dev/issue/41641
+[^ou2020bench]: Changkun Ou. 2020. Conduct Reliable Benchmarking in Go. https://golang.design/s/gobench
+`
+
+func TestParse(t *testing.T) {
+	b := []byte(testPost)
+
+	metaData, err := Meta(b)
+	if err != nil {
+		t.Fatalf("Meta: %v", err)
+	}
+	date, err := Date(metaData)
+	if err != nil {
+		t.Fatalf("Date: %v", err)
+	}
+	if got := date.Format("2006-01-02"); got != "2020-09-30" {
+		t.Fatalf("Date: got %v, want 2020-09-30", got)
+	}
+
+	authors, err := Authors(b)
+	if err != nil {
+		t.Fatalf("Authors: %v", err)
+	}
+	wantAuthors := []Author{
+		{"Changkun Ou", "research@changkun.de"},
+		{"Jane Doe", "jane@example.com"},
+	}
+	if !reflect.DeepEqual(authors, wantAuthors) {
+		t.Fatalf("Authors: got %v, want %v", authors, wantAuthors)
+	}
+
+	if got := Permalink(b); got != "https://golang.design/research/bench-time" {
+		t.Fatalf("Permalink: got %v", got)
+	}
+
+	abstract, err := Abstract(b)
+	if err != nil {
+		t.Fatalf("Abstract: %v", err)
+	}
+	if want := "About six months ago, I did a presentation[^ou2020bench]."; abstract != want {
+		t.Fatalf("Abstract: got %q, want %q", abstract, want)
+	}
+
+	refs, err := References(b)
+	if err != nil {
+		t.Fatalf("References: %v", err)
+	}
+	wantRefs := []Reference{
+		{"ou2020timer", "Changkun Ou. 2020. testing: inconsistent benchmark measurements when interrupts timer.\nThe Go Project Issue Tracker. Sep 26. https://go.dev/issue/41641"},
+		{"ou2020bench", "Changkun Ou. 2020. Conduct Reliable Benchmarking in Go. https://golang.design/s/gobench"},
+	}
+	if !reflect.DeepEqual(refs, wantRefs) {
+		t.Fatalf("References: got %v, want %v", refs, wantRefs)
+	}
+}
+
+func TestCitations(t *testing.T) {
+	got := Citations([]byte(testPost))
+	want := []string{"ou2020bench", "ou2020timer"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Citations: got %v, want %v", got, want)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	b := []byte("See [[bench-time]] and [the handle](research:cgo-handle).\n\n```bash\nif [[x]]; then :; fi\n```\n\nAnd `[[y]]`.\n")
+	got := Links(b)
+	want := []Link{
+		{Slug: "bench-time", Start: 4, Stop: 18},
+		{Slug: "cgo-handle", Text: "the handle", Start: 23, Stop: 56},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Links: got %+v, want %+v", got, want)
+	}
+}
+
+func TestImages(t *testing.T) {
+	b := []byte("![A flame graph](a.png \"Profile\")\n\nSee ![](b.png) inline.\n\n```md\n![](c.png)\n```\n")
+	got := Images(b)
+	want := []Image{
+		{Alt: "A flame graph", Src: "a.png", Title: "Profile", Start: 0, Stop: 33},
+		{Src: "b.png", Start: 39, Stop: 49},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Images: got %+v, want %+v", got, want)
+	}
+	if !got[0].Standalone(b) || got[1].Standalone(b) {
+		t.Fatalf("Standalone: got %v, %v, want true, false", got[0].Standalone(b), got[1].Standalone(b))
+	}
+}
+
+func TestCodeBlocks(t *testing.T) {
+	b := []byte(testPost)
+	blocks := CodeBlocks(b)
+	if len(blocks) != 1 {
+		t.Fatalf("CodeBlocks: got %d blocks, want 1", len(blocks))
+	}
+	if blocks[0].Info != "go" {
+		t.Fatalf("CodeBlocks: got info %q, want go", blocks[0].Info)
+	}
+	if got := string(b[blocks[0].Start:blocks[0].Stop]); got != "x := a[^b]\n" {
+		t.Fatalf("CodeBlocks: got code %q", got)
+	}
+	if got := string(b[blocks[0].Open:blocks[0].Close]); got != "```go\nx := a[^b]\n```\n" {
+		t.Fatalf("CodeBlocks: got block %q", got)
+	}
+
+	b = []byte("```output\n```\n")
+	blocks = CodeBlocks(b)
+	if len(blocks) != 1 || blocks[0].Start != 10 || blocks[0].Stop != 10 || blocks[0].Close != len(b) {
+		t.Fatalf("CodeBlocks: got %+v for an empty block", blocks)
+	}
+}
+
+func TestMissingConventions(t *testing.T) {
+	b := []byte("---\ndate: 2020-09-30T09:02:20+01:00\n---\n\nhello\n")
+	if _, err := Authors(b); err != ErrAuthors {
+		t.Fatalf("Authors: got %v, want %v", err, ErrAuthors)
+	}
+	if _, err := Abstract(b); err != ErrAbstract {
+		t.Fatalf("Abstract: got %v, want %v", err, ErrAbstract)
+	}
+	if _, err := Body(b); err != ErrBody {
+		t.Fatalf("Body: got %v, want %v", err, ErrBody)
+	}
+	if _, err := References(b); err != ErrReferences {
+		t.Fatalf("References: got %v, want %v", err, ErrReferences)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		metaData map[string]any
+		want     string
+	}{
+		{"slug", map[string]any{"slug": "/bench-time/"}, "bench-time"},
+		{"no-slug", map[string]any{}, "pointer-params"},
+		{"empty-slug", map[string]any{"slug": "/"}, "pointer-params"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Slug("content/posts/pointer-params.md", tt.metaData); got != tt.want {
+				t.Fatalf("Slug: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
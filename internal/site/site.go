@@ -0,0 +1,131 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+// Package site implements the configuration of the golang.design research
+// Hugo site, i.e. the parts of config.toml that the tools depend on.
+package site
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Root returns the root directory of the Hugo site that contains dir,
+// i.e. the nearest directory with a config.toml.
+func Root(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "config.toml")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("cannot find config.toml")
+		}
+		dir = parent
+	}
+}
+
+// A Config is the configuration of a site.
+type Config struct {
+	Root       string            // the root directory of the site
+	BaseURL    string            // e.g. https://golang.design/research
+	Title      string            // e.g. golang.design/research
+	Permalinks map[string]string // the permalink pattern of each section
+}
+
+// Load loads the configuration of the site that contains dir.
+//
+// Only string values of the top-level table and the permalinks table
+// are supported, which is all the tools need.
+func Load(dir string) (*Config, error) {
+	root, err := Root(dir)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(root, "config.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{Root: root, Permalinks: map[string]string{}}
+	table := ""
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "[") {
+			table = strings.Trim(line, "[] ")
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.Trim(strings.TrimSpace(v), `"`)
+		switch {
+		case table == "" && k == "baseURL":
+			c.BaseURL = strings.TrimSuffix(v, "/")
+		case table == "" && k == "title":
+			c.Title = v
+		case table == "permalinks":
+			c.Permalinks[k] = v
+		}
+	}
+	return c, s.Err()
+}
+
+// A Page is a page of a site.
+type Page struct {
+	Section  string // e.g. posts
+	Filename string // the file name without extension, e.g. bench-time
+	Slug     string // the slug in the front matter, e.g. /bench-time
+	Title    string
+	Date     time.Time
+}
+
+var rePermalink = regexp.MustCompile(`:(year|month|day|section|slug|filename|title)`)
+var reNonWord = regexp.MustCompile(`[^\pL\pN]+`)
+
+// Permalink returns the permanent URL of a page according to the
+// permalink pattern of its section. Like Hugo, a missing slug falls
+// back to the title.
+func (c *Config) Permalink(p Page) string {
+	pattern, ok := c.Permalinks[p.Section]
+	if !ok {
+		pattern = "/:section/:filename/"
+	}
+	path := rePermalink.ReplaceAllStringFunc(pattern, func(s string) string {
+		switch s {
+		case ":year":
+			return p.Date.Format("2006")
+		case ":month":
+			return p.Date.Format("01")
+		case ":day":
+			return p.Date.Format("02")
+		case ":section":
+			return p.Section
+		case ":slug":
+			if p.Slug != "" {
+				return p.Slug
+			}
+			fallthrough
+		case ":title":
+			return strings.Trim(reNonWord.ReplaceAllString(strings.ToLower(p.Title), "-"), "-")
+		default: // :filename
+			return p.Filename
+		}
+	})
+	for strings.Contains(path, "//") {
+		path = strings.ReplaceAll(path, "//", "/")
+	}
+	return c.BaseURL + "/" + strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package site
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	c, err := Load("../../content/posts")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if c.BaseURL != "https://golang.design/research" {
+		t.Fatalf("Load: got baseURL %q", c.BaseURL)
+	}
+	if c.Title != "golang.design/research" {
+		t.Fatalf("Load: got title %q", c.Title)
+	}
+	if c.Permalinks["posts"] != "/:slug" {
+		t.Fatalf("Load: got permalinks %v", c.Permalinks)
+	}
+}
+
+func TestPermalink(t *testing.T) {
+	c := &Config{
+		BaseURL: "https://golang.design/research",
+		Permalinks: map[string]string{
+			"posts": "/:slug",
+			"notes": "/:year/:month/:title/",
+		},
+	}
+	date := time.Date(2020, 9, 30, 9, 2, 20, 0, time.UTC)
+
+	tests := []struct {
+		page Page
+		want string
+	}{
+		{Page{Section: "posts", Slug: "/bench-time"}, "https://golang.design/research/bench-time"},
+		{Page{Section: "posts", Title: "Go: A Generic Option"}, "https://golang.design/research/go-a-generic-option"},
+		{Page{Section: "notes", Title: "Ultimate Channel", Date: date}, "https://golang.design/research/2020/09/ultimate-channel/"},
+		{Page{Section: "drafts", Filename: "x"}, "https://golang.design/research/drafts/x/"},
+	}
+	for _, tt := range tests {
+		if got := c.Permalink(tt.page); got != tt.want {
+			t.Errorf("Permalink(%+v): got %v, want %v", tt.page, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+// Package xref resolves the cross-references between the golang.design
+// research posts. A post links to another post by its slug, either as
+//
+//	[[bench-time]]
+//
+// which is titled by the linked post, or as
+//
+//	[the benchmark post](research:bench-time)
+//
+// The links resolve to the permalinks of the posts according to the
+// permalinks of config.toml. On the website, the theme renders them as
+// links, and in the PDF they become citations of the posts.
+package xref
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.design/x/research/internal/post"
+	"golang.design/x/research/internal/site"
+)
+
+// Venue is the venue of the posts in their references.
+const Venue = "The golang.design Research"
+
+// An Article is a published post of the site.
+type Article struct {
+	Path    string // the path of the markdown file
+	Name    string // the file name without extension, e.g. bench-time
+	Slug    string // the slug without slashes, e.g. bench-time
+	Title   string
+	URL     string // the permalink
+	Authors []string
+	Tags    []string
+	Date    time.Time
+}
+
+// Load loads the published posts, i.e. not drafts, of the site with the
+// given configuration by slug.
+func Load(c *site.Config) (map[string]*Article, error) {
+	posts, err := filepath.Glob(filepath.Join(c.Root, "content", "posts", "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	articles := map[string]*Article{}
+	for _, p := range posts {
+		a, err := load(c, p)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", filepath.Base(p), err)
+		}
+		if a == nil {
+			continue
+		}
+		if prev, ok := articles[a.Slug]; ok {
+			return nil, fmt.Errorf("%v: slug %v is also used by %v", filepath.Base(p), a.Slug, filepath.Base(prev.Path))
+		}
+		articles[a.Slug] = a
+	}
+	return articles, nil
+}
+
+// load loads the post at path, or returns nil if it is a draft.
+func load(c *site.Config, path string) (*Article, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	metaData, err := post.Meta(b)
+	if err != nil {
+		return nil, err
+	}
+	if draft, _ := metaData["draft"].(bool); draft {
+		return nil, nil
+	}
+	date, err := post.Date(metaData)
+	if err != nil {
+		return nil, err
+	}
+	authors, err := post.Authors(b)
+	if err != nil {
+		return nil, err
+	}
+
+	slug, _ := metaData["slug"].(string)
+	a := &Article{
+		Path:    path,
+		Name:    strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Slug:    post.Slug(path, metaData),
+		Authors: []string{},
+		Tags:    []string{},
+		Date:    date,
+	}
+	a.Title, _ = metaData["title"].(string)
+	a.URL = post.Permalink(b)
+	if a.URL == "" {
+		a.URL = c.Permalink(site.Page{Section: "posts", Filename: a.Name, Slug: slug, Title: a.Title, Date: date})
+	}
+	for _, au := range authors {
+		a.Authors = append(a.Authors, au.Name)
+	}
+	if tags, ok := metaData["tags"].([]any); ok {
+		for _, t := range tags {
+			a.Tags = append(a.Tags, fmt.Sprint(t))
+		}
+	}
+	return a, nil
+}
+
+// Key returns the citation key of the article.
+func (a *Article) Key() string {
+	return "research:" + a.Slug
+}
+
+// Reference returns the reference of the article in the format of the
+// references of a post.
+func (a *Article) Reference() string {
+	return fmt.Sprintf("%v. %v. %v. %v. %v. %v",
+		strings.Join(a.Authors, ", "), a.Date.Year(), a.Title, Venue, a.Date.Format("January 2"), a.URL)
+}
+
+// Cite replaces the links to other posts in the given post by citations
+// of the posts, and appends the definitions of the cited posts to its
+// references. The link text precedes a citation, where the title of the
+// post quoted is the text of [[slug]]. An unknown slug is an error.
+func Cite(b []byte, articles map[string]*Article) ([]byte, error) {
+	links := post.Links(b)
+	if len(links) == 0 {
+		return b, nil
+	}
+
+	var buf strings.Builder
+	cited := []*Article{}
+	last := 0
+	for _, l := range links {
+		a, ok := articles[l.Slug]
+		if !ok {
+			return nil, fmt.Errorf("unknown research article %q in %s", l.Slug, b[l.Start:l.Stop])
+		}
+		text := l.Text
+		if text == "" {
+			text = `"` + a.Title + `"`
+		}
+		fmt.Fprintf(&buf, "%s%s[^%s]", b[last:l.Start], text, a.Key())
+		last = l.Stop
+		if !contains(cited, a) {
+			cited = append(cited, a)
+		}
+	}
+	buf.Write(b[last:])
+
+	if !strings.HasSuffix(buf.String(), "\n") {
+		buf.WriteByte('\n')
+	}
+	for _, a := range cited {
+		fmt.Fprintf(&buf, "[^%s]: %s\n", a.Key(), a.Reference())
+	}
+	return []byte(buf.String()), nil
+}
+
+func contains(articles []*Article, a *Article) bool {
+	for _, x := range articles {
+		if x == a {
+			return true
+		}
+	}
+	return false
+}
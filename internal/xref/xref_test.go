@@ -0,0 +1,93 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package xref
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.design/x/research/internal/site"
+)
+
+func TestLoad(t *testing.T) {
+	c, err := site.Load("../../content/posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	articles, err := Load(c)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	a, ok := articles["bench-time"]
+	if !ok {
+		t.Fatalf("Load: missing bench-time")
+	}
+	if a.Title != "Eliminating A Source of Measurement Errors in Benchmarks" || a.URL != "https://golang.design/research/bench-time" {
+		t.Fatalf("Load: got %+v", a)
+	}
+	want := "Changkun Ou. 2020. Eliminating A Source of Measurement Errors in Benchmarks. The golang.design Research. September 30. https://golang.design/research/bench-time"
+	if got := a.Reference(); got != want {
+		t.Fatalf("Reference: got %q, want %q", got, want)
+	}
+}
+
+func TestCite(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"config.toml": "baseURL = \"https://golang.design/research\"\n[permalinks]\n  posts = \"/:year/:slug/\"\n",
+		"content/posts/handle.md": `---
+date: 2021-06-10T09:00:00+02:00
+slug: /cgo-handle
+title: A Concurrent-safe Centralized Pointer Managing Facility
+---
+
+Author(s): [Jane Doe](mailto:jane[at]example.com)
+`,
+		"content/posts/draft.md": "---\ndate: 2022-01-01T00:00:00Z\ntitle: Draft\ndraft: true\n---\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := site.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	articles, err := Load(c)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(articles) != 1 || articles["cgo-handle"].URL != "https://golang.design/research/2021/cgo-handle/" {
+		t.Fatalf("Load: got %v", articles)
+	}
+
+	b := "See [[cgo-handle]] and [the handle](research:cgo-handle).\n\n## References\n\n[^x]: X.\n"
+	got, err := Cite([]byte(b), articles)
+	if err != nil {
+		t.Fatalf("Cite: %v", err)
+	}
+	want := `See "A Concurrent-safe Centralized Pointer Managing Facility"[^research:cgo-handle] and the handle[^research:cgo-handle].
+
+## References
+
+[^x]: X.
+[^research:cgo-handle]: Jane Doe. 2021. A Concurrent-safe Centralized Pointer Managing Facility. The golang.design Research. June 10. https://golang.design/research/2021/cgo-handle/
+`
+	if string(got) != want {
+		t.Fatalf("Cite: got\n%s\nwant\n%s", got, want)
+	}
+
+	for _, b := range []string{"See [[draft]].", "See [x](research:none)."} {
+		if _, err := Cite([]byte(b), articles); err == nil || !strings.Contains(err.Error(), "unknown research article") {
+			t.Fatalf("Cite(%q): got error %v", b, err)
+		}
+	}
+}
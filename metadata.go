@@ -0,0 +1,97 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.design/x/research/internal/post"
+	"golang.design/x/research/internal/site"
+)
+
+// properties are the document properties of a generated PDF. They are
+// written to both the Info dictionary and the XMP metadata stream.
+type properties struct {
+	Title    string
+	Authors  []string
+	Subject  string
+	Keywords []string
+	Date     time.Time
+	URL      string
+}
+
+// parseProperties collects the document properties of the markdown
+// file at the given path from its front matter and conventions.
+func parseProperties(path string, b []byte, metaData map[string]any, abstract string, date time.Time, authors []post.Author) properties {
+	p := properties{Date: date}
+	p.Title, _ = metaData["title"].(string)
+	for _, a := range authors {
+		p.Authors = append(p.Authors, a.Name)
+	}
+	p.Subject = post.PlainText(abstract)
+	if tags, ok := metaData["tags"].([]any); ok {
+		for _, t := range tags {
+			p.Keywords = append(p.Keywords, fmt.Sprint(t))
+		}
+	}
+	p.URL = post.Permalink(b)
+	if p.URL == "" {
+		if c, err := site.Load(filepath.Dir(path)); err == nil && c.BaseURL != "" {
+			slug, _ := metaData["slug"].(string)
+			p.URL = c.Permalink(site.Page{
+				Section:  "posts",
+				Filename: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+				Slug:     slug,
+				Title:    p.Title,
+				Date:     date,
+			})
+		}
+	}
+	return p
+}
+
+// apply sets the pandoc variables that hyperref uses for the Info
+// dictionary of the PDF.
+func (p properties) apply(metaData map[string]any) {
+	metaData["title-meta"] = p.Title
+	metaData["author-meta"] = strings.Join(p.Authors, ", ")
+	metaData["subject"] = p.Subject
+	metaData["keywords"] = p.Keywords
+}
+
+// preamble returns the LaTeX preamble that additionally embeds the
+// properties as XMP metadata. The title, authors, subject and keywords
+// are picked up by hyperxmp from the hyperref settings.
+func (p properties) preamble() string {
+	opts := []string{
+		"pdfcreationdate={" + p.Date.Format("D:20060102150405") + pdfZone(p.Date) + "}",
+		"pdfdate={" + p.Date.Format("2006-01-02") + "}",
+		"pdflang={en}",
+	}
+	if p.URL != "" {
+		opts = append(opts,
+			"pdfurl={"+texText(p.URL)+"}",
+			"pdfidentifier={"+texText(p.URL)+"}")
+	}
+	return `\usepackage{hyperxmp}
+	\hypersetup{
+		` + strings.Join(opts, ",\n\t\t") + `
+	}`
+}
+
+// pdfZone formats the time zone of t as required by PDF dates, e.g. +01'00'.
+func pdfZone(t time.Time) string {
+	_, offset := t.Zone()
+	if offset == 0 {
+		return "Z"
+	}
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("%c%02d'%02d'", sign, offset/3600, offset%3600/60)
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPdfZone(t *testing.T) {
+	for _, tt := range []struct {
+		offset int // in seconds east of UTC
+		want   string
+	}{
+		{0, "Z"},
+		{3600, "+01'00'"},
+		{5*3600 + 30*60, "+05'30'"},
+		{-(3*3600 + 30*60), "-03'30'"},
+		{-10 * 3600, "-10'00'"},
+	} {
+		d := time.Date(2020, time.September, 30, 9, 2, 20, 0, time.FixedZone("", tt.offset))
+		if got := pdfZone(d); got != tt.want {
+			t.Fatalf("pdfZone(%v): got %v, want %v", d, got, tt.want)
+		}
+	}
+}
+
+func TestParseProperties(t *testing.T) {
+	root := t.TempDir()
+	config := "baseURL = \"https://golang.design/research/\"\n\n[permalinks]\nposts = \"/:slug/\"\n"
+	if err := os.WriteFile(filepath.Join(root, "config.toml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "content", "posts")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2020, time.September, 30, 9, 2, 20, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name     string
+		path     string
+		b        string
+		metaData map[string]any
+		want     string
+	}{
+		{"permalink", filepath.Join(dir, "bench-time.md"),
+			"Permalink: https://golang.design/research/bench-time\n",
+			map[string]any{"slug": "/other"},
+			"https://golang.design/research/bench-time"},
+		{"slug", filepath.Join(dir, "bench-time.md"), "",
+			map[string]any{"slug": "/bench-time"},
+			"https://golang.design/research/bench-time/"},
+		{"title", filepath.Join(dir, "bench-time.md"), "",
+			map[string]any{"title": "Eliminating Errors in Benchmarks"},
+			"https://golang.design/research/eliminating-errors-in-benchmarks/"},
+		{"no-site", filepath.Join(t.TempDir(), "bench-time.md"), "",
+			map[string]any{"slug": "/bench-time"},
+			""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parseProperties(tt.path, []byte(tt.b), tt.metaData, "", date, nil)
+			if p.URL != tt.want {
+				t.Fatalf("parseProperties: got URL %q, want %q", p.URL, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreamble(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		p    properties
+		want []string
+		omit []string
+	}{
+		{"zone", properties{
+			Date: time.Date(2020, time.September, 30, 9, 2, 20, 0, time.FixedZone("CET", 3600)),
+		}, []string{
+			"pdfcreationdate={D:20200930090220+01'00'}",
+			"pdfdate={2020-09-30}",
+		}, []string{"pdfurl", "pdfidentifier"}},
+		{"url", properties{
+			Date: time.Date(2020, time.September, 30, 9, 2, 20, 0, time.UTC),
+			URL:  "https://golang.design/research/a_b?x=1&y=50%#top",
+		}, []string{
+			"pdfcreationdate={D:20200930090220Z}",
+			`pdfurl={https://golang.design/research/a\_b?x=1\&y=50\%\#top}`,
+			`pdfidentifier={https://golang.design/research/a\_b?x=1\&y=50\%\#top}`,
+		}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.p.preamble()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Fatalf("preamble: got\n%s\nwant it to contain %s", got, want)
+				}
+			}
+			for _, omit := range tt.omit {
+				if strings.Contains(got, omit) {
+					t.Fatalf("preamble: got\n%s\nwant no %s", got, omit)
+				}
+			}
+		})
+	}
+}
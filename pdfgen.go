@@ -4,87 +4,221 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
-	"github.com/yuin/goldmark"
-	meta "github.com/yuin/goldmark-meta"
-	"github.com/yuin/goldmark/parser"
+	"golang.design/x/research/internal/bib"
+	"golang.design/x/research/internal/post"
+	"golang.design/x/research/internal/site"
+	"golang.design/x/research/internal/xref"
 	"gopkg.in/yaml.v3"
 	"mvdan.cc/xurls/v2"
 )
 
-var md goldmark.Markdown
+var (
+	anonymous = flag.Bool("anonymous", false, "strip authors and identifying metadata for double-blind submissions")
+	attach    = flag.Bool("attach", true, "attach the companion source code in content/assets to the pdf")
+)
 
-func init() {
-	md = goldmark.New(
-		goldmark.WithExtensions(
-			meta.Meta,
-		),
-	)
-}
+// reRunnable matches the opening fence of a runnable code block, whose
+// info string "go run [dir]" is reduced to "go" for syntax highlighting.
+var reRunnable = regexp.MustCompile("(?m)^(\\s*```+\\s*go) run\\b.*$")
 
 func usage() {
 	fmt.Fprintf(os.Stderr, `pdfgen converts a golang.design research markdown file to a pdf.
 
-usage: pdfgen bench-time.md
+usage: pdfgen [-anonymous] [-attach=false] bench-time.md
+       pdfgen bundle bench-time.md
+       pdfgen slides bench-time.md
+       pdfgen bib ../posts
 `)
+	flag.PrintDefaults()
 }
 
 func main() {
+	flag.Usage = usage
 	flag.Parse()
 	args := flag.Args()
-	if len(args) != 1 {
+	switch {
+	case len(args) == 2 && args[0] == "bundle":
+		bundle(args[1])
+	case len(args) == 2 && args[0] == "slides":
+		slides(args[1])
+	case len(args) == 2 && args[0] == "bib":
+		checkBibliography(args[1])
+	case len(args) == 1:
+		path := args[0]
+		a := loadArticle(path)
+		dst := "../" + strings.TrimSuffix(path, ".md")
+		if *anonymous {
+			dst += "-anonymous"
+		}
+		a.render(dst + ".pdf")
+	default:
 		usage()
-		return
 	}
-	path := args[0]
+}
+
+// article is a research article that is prepared for pandoc.
+type article struct {
+	path       string
+	src        []byte // the markdown with cross-references as citations
+	date       time.Time
+	authors    []post.Author
+	props      properties
+	metaData   map[string]any
+	body       string
+	references string
+	assets     string // the attached asset directory, if any
+}
 
+func loadArticle(path string) *article {
 	// Only deal with .md files
 	if !strings.HasSuffix(path, ".md") {
 		log.Fatalf("pdfgen: input file must be a markdown file.")
-		return
 	}
 
 	b, err := os.ReadFile(path)
 	if err != nil {
 		log.Fatalf("pdfgen: failed to load the given markdown file.")
 	}
+	b = crossReferences(path, b)
 
-	var buf bytes.Buffer
-	context := parser.NewContext()
-	if err := md.Convert(b, &buf, parser.WithContext(context)); err != nil {
+	metaData, err := post.Meta(b)
+	if err != nil {
 		log.Fatal(err)
 	}
-	metaData := meta.Get(context)
-	convertDate(metaData)
+	date, err := post.Date(metaData)
+	if err != nil {
+		log.Fatalf("pdfgen: %v", err)
+	}
+	metaData["date"] = date.Format("January 02, 2006")
 
-	authors := parseAuthor(b)
-	metaData["author"] = authors
+	authors, err := post.Authors(b)
+	if err != nil {
+		log.Fatalf("pdfgen: %v", err)
+	}
+	metaData["author"] = authorStrings(authors)
 
-	abstrat := parseAbstract(b)
-	// https://stackoverflow.com/questions/1919982/regex-smallest-possible-match-or-nongreedy-match
-	re := regexp.MustCompile("\\[\\^(.*?)\\]")
-	abstrat = re.ReplaceAllString(abstrat, "\\cite{$1}") // use citation key
+	abstrat, err := post.Abstract(b)
+	if err != nil {
+		log.Fatalf("pdfgen: %v", err)
+	}
+	props := parseProperties(path, b, metaData, abstrat, date, authors)
+	abstrat = post.ReplaceCitations(abstrat, "\\cite{$1}") // use citation key
 	metaData["abstract"] = abstrat
 
-	metaData["header-includes"] = `\usepackage{fancyhdr}
-    \pagestyle{fancy}
-	\fancyhead[LE,RO]{\rightmark}
-    \fancyhead[RE,LO]{The golang.design Research}
-    \fancyfoot{}
-	\fancyfoot[C]{\thepage}`
+	runningHead := xref.Venue
+	if *anonymous {
+		runningHead = ""
+		anonymize(metaData, &props)
+		reportIdentities(path, b, authors)
+	}
+	props.apply(metaData)
+	metaData["header-includes"] = []string{headerIncludes(runningHead), props.preamble()}
+
+	// The companion source code may contain the names of the authors,
+	// thus it is not attached to anonymous articles.
+	assets := ""
+	if *attach && !*anonymous {
+		assets = assetDir(path, metaData)
+	}
+
+	body, err := post.Body(b)
+	if err != nil {
+		log.Fatalf("pdfgen: %v", err)
+	}
+	body = post.ReplaceCitations(body, "\\cite{$1}") // use citation key
+	body = reRunnable.ReplaceAllString(body, "$1")   // see cmd/postrun
+	body = benchTables(path, body, latexTables)
+	body, charts := benchCharts(path, body)
+	if charts {
+		metaData["header-includes"] = append(metaData["header-includes"].([]string), `\usepackage{tikz}`)
+	}
+	body, figs := figures(body)
+	if charts || figs {
+		metaData["header-includes"] = append(metaData["header-includes"].([]string), `\usepackage{pdfcomment}`)
+	}
+
+	references := bibliography(references(path, b))
+	if *anonymous {
+		baseURL := ""
+		if c, err := site.Load(filepath.Dir(path)); err == nil {
+			baseURL = c.BaseURL
+		}
+		references = maskSelfCitations(references, authors, baseURL)
+	}
+
+	return &article{
+		path:       path,
+		src:        b,
+		date:       date,
+		authors:    authors,
+		props:      props,
+		metaData:   metaData,
+		body:       body,
+		references: references,
+		assets:     assets,
+	}
+}
+
+// crossReferences replaces the links to other posts in the markdown
+// file at path with content b by citations of the posts.
+func crossReferences(path string, b []byte) []byte {
+	if len(post.Links(b)) == 0 {
+		return b
+	}
+	c, err := site.Load(filepath.Dir(path))
+	if err != nil {
+		log.Fatalf("pdfgen: cannot resolve links to other posts: %v", err)
+	}
+	articles, err := xref.Load(c)
+	if err != nil {
+		log.Fatalf("pdfgen: cannot resolve links to other posts: %v", err)
+	}
+	b, err = xref.Cite(b, articles)
+	if err != nil {
+		log.Fatalf("pdfgen: %v", err)
+	}
+	return b
+}
+
+// references returns the references of the markdown file at path with
+// content b, resolved against the site-level bibliography.
+func references(path string, b []byte) []post.Reference {
+	refs, err := post.References(b)
+	if err != nil {
+		log.Fatalf("pdfgen: %v", err)
+	}
+	bibl, err := bib.Load(filepath.Dir(path))
+	if err != nil {
+		log.Fatalf("pdfgen: cannot load bibliography: %v", err)
+	}
+	refs, err = bibl.Resolve(refs, post.Citations(b))
+	if err != nil {
+		log.Fatalf("pdfgen: %v", err)
+	}
+	return refs
+}
 
-	body := parseBody(b)
-	body = re.ReplaceAllString(body, "\\cite{$1}") // use citation key
+// render converts the article to the given destination using pandoc.
+// The output format is determined by the extension of dst, and args
+// are passed to pandoc as additional arguments.
+func (a *article) render(dst string, args ...string) {
+	metaData := make(map[string]any, len(a.metaData))
+	for k, v := range a.metaData {
+		metaData[k] = v
+	}
+	if a.assets != "" {
+		metaData["header-includes"] = append(metaData["header-includes"].([]string), `\usepackage{embedfile}`)
+	}
 
 	head, err := yaml.Marshal(metaData)
 	if err != nil {
@@ -95,13 +229,12 @@ func main() {
 %v
 ---
 %v
-`, string(head), body)
+`, string(head), a.body)
 
 	// Prepare all content.
 
 	ref := "ref.tex"
-	references := parseReferences(b)
-	if err := os.WriteFile(ref, []byte(references), os.ModePerm); err != nil {
+	if err := os.WriteFile(ref, []byte(a.references), os.ModePerm); err != nil {
 		log.Fatalf("pdfgen: cannot create reference file: %v", err)
 	}
 	defer os.Remove(ref)
@@ -112,150 +245,60 @@ func main() {
 	}
 	defer os.Remove(article)
 
+	inputs := []string{article, ref}
+	if a.assets != "" {
+		archive, appendix := writeAssetAppendix(a.assets, a.date)
+		defer os.Remove(archive)
+		defer os.Remove(appendix)
+		inputs = append(inputs, appendix)
+	}
+
 	// Generate pdf
 
-	dst := "../" + strings.TrimSuffix(path, ".md") + ".pdf"
-	cmd := exec.Command("pandoc", article, ref,
+	cmd := exec.Command("pandoc", append(inputs, append([]string{
 		"-V", "linkcolor:blue",
 		"--pdf-engine=xelatex",
-		"-o", dst)
+		"-o", dst}, args...)...)...)
 	log.Println(cmd.String())
 	if b, err := cmd.CombinedOutput(); err != nil {
 		log.Fatal(string(b))
 	}
-	return
 }
 
-func convertDate(metaData map[string]any) {
-	dateRaw, ok := metaData["date"]
-	if !ok {
-		log.Fatalf("pdfgen: metadata missing date information.")
+// authorStrings returns the authors as pandoc metadata, where the
+// emails of the authors are footnotes.
+func authorStrings(authors []post.Author) []string {
+	ss := make([]string, 0, len(authors))
+	for _, a := range authors {
+		ss = append(ss, fmt.Sprintf("%v^[Email: %v]", a.Name, a.Email))
 	}
-	date, ok := dateRaw.(string)
-	if !ok {
-		log.Fatalf("pdfgen: metadata contains invalid date format.")
-	}
-	t, err := time.Parse("2006-01-02T15:04:05Z07:00", date)
-	if err != nil {
-		log.Fatalf("pdfgen: cannot parse date: %v", err)
-	}
-	metaData["date"] = t.Format("January 02, 2006")
+	return ss
 }
 
-type author struct {
-	Name  string
-	Email string
-}
-
-func (a author) String() string {
-	return fmt.Sprintf("%v^[Email: %v]", a.Name, a.Email)
-}
-
-func parseAuthor(b []byte) []string {
-	s := bufio.NewScanner(bytes.NewReader(b))
-	authors := []string{}
-
-	for s.Scan() {
-		l := s.Text()
-		if strings.HasPrefix(l, "Author(s): ") {
-			authorsStr := strings.TrimPrefix(l, "Author(s): ")
-			authorList := strings.Split(authorsStr, ", ")
-
-			for _, a := range authorList {
-				before, after, ok := strings.Cut(a, "](")
-				if !ok {
-					continue
-				}
-				name := strings.TrimPrefix(before, "[")
-				email := strings.TrimPrefix(strings.TrimSuffix(after, ")"), "mailto:")
-				email = strings.ReplaceAll(email, "[at]", "@")
-				authors = append(authors, author{name, email}.String())
-			}
-		}
-	}
-
-	if len(authors) == 0 {
-		log.Fatalf(`pdfgen: cannot find authors, make sure the markdown uses the correct convention:
-
-Author(s): [FirstName LastName](mailto:email), [FirstName LastName](mailto:email)`)
-	}
-
-	return authors
-}
-
-func parseAbstract(b []byte) string {
-	content := string(b)
-
-	var ok bool
-	_, content, ok = strings.Cut(content, "<!--abstract-->\n")
-	if !ok {
-		goto err
-	}
-	content, _, ok = strings.Cut(content, "\n<!--more-->")
-	if !ok {
-		goto err
-	}
-
-	return content
-
-err:
-	log.Fatal(`pdfgen: cannot find abstract, make sure the markdown uses the correct convention:
-
-	<!--abstract-->
-	abstract content goes here...
-	<!--more-->
-	`)
-	return ""
-}
-
-func parseBody(b []byte) string {
-	content := string(b)
-
-	var ok bool
-	_, content, ok = strings.Cut(content, "\n<!--more-->")
-	if !ok {
-		goto err
-	}
-	content, _, ok = strings.Cut(content, "## References")
-	if !ok {
-		goto err
-	}
-	return content
-
-err:
-	log.Fatal(`pdfgen: cannot find body, make sure the markdown uses the correct convention:
-
-	<!--more-->
-
-	content body...
-
-	## References
-	`)
-	return ""
+// headerIncludes returns the LaTeX preamble that sets up the page
+// style, using head as the running head of the article.
+func headerIncludes(head string) string {
+	return `\usepackage{fancyhdr}
+    \pagestyle{fancy}
+	\fancyhead[LE,RO]{\rightmark}
+    \fancyhead[RE,LO]{` + head + `}
+    \fancyfoot{}
+	\fancyfoot[C]{\thepage}`
 }
 
-func parseReferences(b []byte) string {
-	content := string(b)
-
-	var ok bool
-	_, content, ok = strings.Cut(content, "## References\n")
-	if !ok {
-		log.Fatal(`pdfgen: cannot find references, make sure the markdown uses the correct convention:
-
-		## References
-
-		[^ou2022bench]: Changkun Ou. 2020. Conduct Reliable Benchmarking in Go. TalkGo Meetup. Virtual Event. March 26. https://golang.design/s/gobench
-		`)
-
+// bibliography returns the given references as a LaTeX bibliography.
+func bibliography(refs []post.Reference) string {
+	var w strings.Builder
+	w.WriteString("\\begin{thebibliography}{99}\n\n")
+	for _, ref := range refs {
+		fmt.Fprintf(&w, "\\bibitem{%v} %v\n", ref.Key, ref.Text)
 	}
-
-	content = strings.ReplaceAll(content, "[^", "\\bibitem{")
-	content = strings.ReplaceAll(content, "]:", "}")
+	content := w.String()
 
 	rxStrict := xurls.Strict()
 	urls := rxStrict.FindAllString(content, -1)
 	for _, url := range urls {
 		content = strings.ReplaceAll(content, url, "\\url{"+url+"}")
 	}
-	return "\\begin{thebibliography}{99}" + content + "\\end{thebibliography}"
+	return content + "\\end{thebibliography}"
 }
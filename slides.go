@@ -0,0 +1,188 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.design/x/research/internal/benchchart"
+	"golang.design/x/research/internal/post"
+)
+
+// refsPerSlide is the number of references on a slide of a present
+// deck, which does not break long slides.
+const refsPerSlide = 8
+
+var (
+	reSection    = regexp.MustCompile(`(?m)^## (.*)$`)
+	reSubsection = regexp.MustCompile(`(?m)^###+ (.*)$`)
+	reRawHTML    = regexp.MustCompile(`\{\{<\s*/?rawhtml\s*>\}\}`)
+)
+
+// slides converts the markdown file at the given path to slide decks
+// for presenting the article at meetups. A slide starts at each ##
+// heading, and the references are the last slides. Two decks are
+// written next to the generated PDF:
+//
+//	<name>.slide        a deck of the Go present tool
+//	<name>-slides.pdf   a Beamer deck
+func slides(mdpath string) {
+	a := loadArticle(mdpath)
+	dst := "../" + strings.TrimSuffix(mdpath, ".md")
+	if *anonymous {
+		dst += "-anonymous"
+	}
+	if err := os.WriteFile(dst+".slide", presentDeck(a), 0644); err != nil {
+		log.Fatalf("pdfgen: cannot write slides: %v", err)
+	}
+	log.Printf("pdfgen: slides written to %v.slide", dst)
+	a.beamer().render(dst+"-slides.pdf", "-t", "beamer", "--slide-level=2")
+}
+
+// beamer returns the article prepared for a Beamer deck.
+func (a *article) beamer() *article {
+	s := *a
+	s.assets = ""
+	s.metaData = make(map[string]any, len(a.metaData))
+	for k, v := range a.metaData {
+		s.metaData[k] = v
+	}
+	delete(s.metaData, "abstract")
+
+	// The page style of the article does not apply to frames, and the
+	// emails of the authors do not fit the title frame.
+	includes := []string{}
+	for _, inc := range a.metaData["header-includes"].([]string) {
+		if !strings.HasPrefix(inc, `\usepackage{fancyhdr}`) {
+			includes = append(includes, inc)
+		}
+	}
+	s.metaData["header-includes"] = includes
+	if !*anonymous {
+		names := []string{}
+		for _, au := range a.authors {
+			names = append(names, au.Name)
+		}
+		s.metaData["author"] = names
+	}
+
+	// Long sections and the bibliography continue on the next frame.
+	body := mapCode(a.body, func(text string) string {
+		return reSection.ReplaceAllString(text, "## $1 {.allowframebreaks}")
+	}, nil)
+	s.body = "## Abstract\n\n" + a.metaData["abstract"].(string) + "\n" + body +
+		"\n## References {.allowframebreaks}\n"
+	return &s
+}
+
+// presentDeck returns the article as a deck of the Go present tool, see
+// https://pkg.go.dev/golang.org/x/tools/present. The deck uses the
+// markdown syntax of present and is written to the parent directory,
+// relative to which the figures are referenced.
+func presentDeck(a *article) []byte {
+	refs := references(a.path, a.src)
+	cite := func(s string) string {
+		for i, ref := range refs {
+			s = strings.ReplaceAll(s, "[^"+ref.Key+"]", fmt.Sprintf("[%d]", i+1))
+		}
+		return s
+	}
+
+	var w strings.Builder
+	fmt.Fprintf(&w, "# %v\n%v\n", a.props.Title, a.date.Format("2 Jan 2006"))
+	if len(a.props.Keywords) > 0 {
+		fmt.Fprintf(&w, "Tags: %v\n", strings.Join(a.props.Keywords, ", "))
+	}
+	fmt.Fprintf(&w, "Summary: %v\n", a.props.Subject)
+	if !*anonymous {
+		for _, au := range a.authors {
+			fmt.Fprintf(&w, "\n%v\n%v\n", au.Name, au.Email)
+		}
+	}
+
+	abstract, err := post.Abstract(a.src)
+	if err != nil {
+		log.Fatalf("pdfgen: %v", err)
+	}
+	fmt.Fprintf(&w, "\n## Abstract\n\n%v\n", cite(abstract))
+	if a.props.URL != "" {
+		fmt.Fprintf(&w, "\n%v\n", a.props.URL)
+	}
+
+	body, err := post.Body(a.src)
+	if err != nil {
+		log.Fatalf("pdfgen: %v", err)
+	}
+	body = reRunnable.ReplaceAllString(body, "$1") // see cmd/postrun
+	w.WriteString(mapCode(body, func(text string) string {
+		return presentText(a.path, cite(text))
+	}, func(code string) string {
+		// Present drops the lines starting with // as comments, which
+		// are kept in an indented code block.
+		lines := strings.SplitAfter(code, "\n")
+		for i, l := range lines {
+			if strings.TrimSpace(l) != "" {
+				lines[i] = "\t" + l
+			}
+		}
+		return "\n" + strings.Join(lines, "")
+	}))
+
+	for i, ref := range refs {
+		if i%refsPerSlide == 0 {
+			w.WriteString("\n## References\n")
+		}
+		fmt.Fprintf(&w, "\n[%d] %v\n", i+1, strings.Join(strings.Fields(ref.Text), " "))
+	}
+	return []byte(w.String())
+}
+
+// presentText converts the text outside of the code blocks of the
+// markdown file at mdpath to the syntax of present.
+func presentText(mdpath, text string) string {
+	text = benchTables(mdpath, text, textTables)
+	for _, sc := range benchchart.Shortcodes([]byte(text)) {
+		text = strings.Replace(text, sc.Text, ".image ../"+benchchart.Dir+"/"+sc.Params["name"]+".svg _ 640", 1)
+	}
+	text = reRawHTML.ReplaceAllString(text, "")
+	text = reSubsection.ReplaceAllString(text, "**$1**")
+	return replaceImages(text, func(img post.Image) string {
+		src := img.Src
+		if !strings.Contains(src, "://") {
+			src = path.Join("posts", src)
+		}
+		s := ".image " + src + " _ 720"
+		if img.Title != "" {
+			s += "\n.caption " + img.Title
+		}
+		return s
+	})
+}
+
+// mapCode replaces the text outside of the fenced code blocks of the
+// markdown s by text(t), and the code blocks by code(c), where c is the
+// code without fences. A nil function leaves its parts unchanged.
+func mapCode(s string, text, code func(string) string) string {
+	if text == nil {
+		text = func(t string) string { return t }
+	}
+	var w strings.Builder
+	last := 0
+	for _, c := range post.CodeBlocks([]byte(s)) {
+		w.WriteString(text(s[last:c.Open]))
+		if code != nil {
+			w.WriteString(code(s[c.Start:c.Stop]))
+		} else {
+			w.WriteString(s[c.Open:c.Close])
+		}
+		last = c.Close
+	}
+	w.WriteString(text(s[last:]))
+	return w.String()
+}
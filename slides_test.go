@@ -0,0 +1,51 @@
+// Copyright 2022 The golang.design Initiative.
+// All rights reserved. Created by Changkun Ou <changkun.de>
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"golang.design/x/research/internal/post"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestPresentDeck(t *testing.T) {
+	const path = "testdata/deck.md"
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &article{
+		path:    path,
+		src:     b,
+		date:    time.Date(2020, time.September, 30, 9, 2, 20, 0, time.UTC),
+		authors: []post.Author{{Name: "Jane Doe", Email: "jane@example.com"}},
+		props: properties{
+			Title:    "A Deck",
+			Subject:  "The abstract cites a talk.",
+			Keywords: []string{"Benchmark"},
+			URL:      "https://golang.design/research/deck",
+		},
+	}
+	got := presentDeck(a)
+
+	const golden = "testdata/deck.slide"
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("presentDeck: got\n%s\nwant\n%s", got, want)
+	}
+}